@@ -3,6 +3,9 @@ set -e
 
 echo "🔍 Starting System Audit..."
 
+# FTS5 (/find) is opt-in in go-sqlite3
+TAGS="sqlite_fts5"
+
 echo "1. Checking Compliance (Static Analysis)..."
 go run cmd/compliance/main.go
 
@@ -14,16 +17,22 @@ go run cmd/test_parser/main.go
 
 echo "3. Testing Indexer..."
 go get github.com/mattn/go-sqlite3 # ensure driver
-go run cmd/test_indexer/main.go
+go run -tags "$TAGS" cmd/test_indexer/main.go
 
 echo "4. Testing Bot Ops..."
-go run cmd/test_ops/main.go
-go test -v ./internal/bot/...
+go test -tags "$TAGS" -v ./internal/bot/...
 
 echo "5. Testing AI Permissions..."
 go run cmd/test_ai/main.go
 
-echo "6. Building Binary..."
-go build -o zettelbot cmd/bot/main.go
+echo "6. Vault Integrity..."
+if [ -n "$ZETTEL_ROOT" ]; then
+    go run -tags "$TAGS" ./cmd/zettel doctor -root "$ZETTEL_ROOT" -db "$(mktemp -d)/doctor.db"
+else
+    echo "   (skipped: ZETTEL_ROOT not set)"
+fi
+
+echo "7. Building Binary..."
+go build -tags "$TAGS" -o zettel ./cmd/zettel
 
 echo "✅ System Verified. Ready for deployment."
@@ -28,7 +28,7 @@ func main() {
 			return nil
 		}
 		// Skip binaries and git
-		if strings.HasPrefix(path, ".") || strings.HasSuffix(path, ".db") || strings.HasSuffix(path, ".exe") || path == "zettelbot" {
+		if strings.HasPrefix(path, ".") || strings.HasSuffix(path, ".db") || strings.HasSuffix(path, ".exe") || path == "zettelbot" || path == "zettel" {
 			return nil
 		}
 
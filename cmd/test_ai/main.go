@@ -1,36 +1,49 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"time"
 
 	"github.com/eliseohh/zettelcornelbot/internal/neural"
 )
 
 func main() {
-	// Mock Ollama Server
+	// Mock Ollama Server: answers every structured request with a valid draft
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintln(w, `{"response":"Mock AI Response"}`)
+		draft, _ := json.Marshal(map[string]any{
+			"title":   "Mock Note",
+			"notas":   "Mock AI Response",
+			"cues":    []string{"What is a mock?"},
+			"resumen": "Mock AI Response",
+			"links":   []string{},
+		})
+		json.NewEncoder(w).Encode(map[string]string{"response": string(draft)})
 	}))
 	defer ts.Close()
 
-	client := neural.NewClient(ts.URL, "test")
+	client := neural.NewOllama(ts.URL, "test")
 
 	// Test Summarize
-	resp, err := client.Summarize("Content")
+	resp, err := neural.Summarize(client, "Content")
 	if err != nil {
 		panic(err)
 	}
-	if resp != "Mock AI Response" {
+	if !strings.Contains(resp, "Mock AI Response") {
 		panic("Unexpected response")
 	}
 	fmt.Println("✔ Summarize Permissions OK (Read-Only)")
 
-	// Test Draft
-	resp, err = client.Draft("Topic")
+	// Test Draft: returned, never written
+	draft, err := neural.ProposeDraft(client, "Topic", "idea", time.Now(), nil)
 	if err != nil {
 		panic(err)
 	}
+	if draft.Title != "Mock Note" {
+		panic("Unexpected draft")
+	}
 	fmt.Println("✔ Draft Permissions OK (Read-Only)")
 }
@@ -28,6 +28,10 @@ Tipo: idea
 ## Notas
 Links to [[note-b]]
 
+## Cues
+
+## Resumen
+
 ## Enlaces
 - [[note-b]]`), 0644)
 
@@ -39,6 +43,10 @@ Tipo: idea
 ## Notas
 Back to [[note-a]]
 
+## Cues
+
+## Resumen
+
 ## Enlaces
 - [[note-a]]`), 0644)
 
@@ -48,8 +56,9 @@ Back to [[note-a]]
 		panic(err)
 	}
 
-	schema, _ := index.ReadSchemaFile("internal/index/schema.sql")
-	db.InitSchema(schema)
+	if err := db.Migrate(); err != nil {
+		panic(err)
+	}
 
 	// Run Sync
 	idx := index.NewIndexer(db)
@@ -76,6 +85,10 @@ Tipo: idea
 ## Notas
 Links to [[note-b]] and [[note-c]]
 
+## Cues
+
+## Resumen
+
 ## Enlaces
 - [[note-b]]
 - [[note-c]]`), 0644)
@@ -27,40 +27,73 @@ Short summary.
 ## Enlaces
 - [[link]]
 `
-	testParse("valid", validContent, true)
+	testParse("valid", validContent)
 
-	// Case 2: Invalid Cue
-	invalidCue := `# Title
-Fecha: 2024-02-02
-Tipo: idea
+	// note builds a spec-compliant note around the given title and cues
+	note := func(title string, cues ...string) string {
+		body := "# " + title + "\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\nContent.\n\n## Cues\n"
+		for _, c := range cues {
+			body += "- " + c + "\n"
+		}
+		return body + "\n## Resumen\nShort summary.\n\n## Enlaces\n- [[link]]\n"
+	}
 
-## Cues
-- Missing question mark
-`
-	testParse("invalid_cue", invalidCue, false)
+	// Case 2: Invalid Cue
+	testParse("invalid_cue", note("Title", "Missing question mark"), markdown.CueMissingQuestionMark)
 
 	// Case 3: Title too long
-	longTitle := "# " + strings.Repeat("A", 121) + "\nFecha: 2024\n"
-	testParse("long_title", longTitle, false)
+	testParse("long_title", note(strings.Repeat("A", 121)), markdown.TitleTooLong)
+
+	// Case 4: Every violation reported in one pass
+	several := note(strings.Repeat("B", 121), "No question mark", strings.Repeat("C", 121)+"?")
+	testParse("several", several, markdown.TitleTooLong, markdown.CueMissingQuestionMark, markdown.CueTooLong)
+
+	// Case 5: Metadata (MARKDOWN_SPEC rule 3)
+	badMeta := strings.Replace(strings.Replace(validContent, "Fecha: 2024-02-02", "Fecha: 2 de febrero", 1), "Tipo: idea", "Tipo: poema", 1)
+	testParse("bad_metadata", badMeta, markdown.InvalidFecha, markdown.InvalidTipo)
+
+	noMeta := strings.Replace(validContent, "Fecha: 2024-02-02\nTipo: idea\n", "", 1)
+	testParse("missing_metadata", noMeta, markdown.MissingFecha, markdown.MissingTipo)
+
+	// Case 6: Sections: all required, in order, nothing else
+	reordered := strings.Replace(validContent, "## Resumen\nShort summary.\n\n", "", 1)
+	reordered = strings.Replace(reordered, "## Notas", "## Resumen\nShort summary.\n\n## Extra\n\n## Notas", 1)
+	testParse("sections", reordered, markdown.SectionOutOfOrder, markdown.UnknownSection)
+
+	// Case 7: Lenient mode only warns on structure, limits still fail
+	markdown.DefaultMode = markdown.Lenient
+	testParse("lenient_structure", noMeta)
+	testParse("lenient_limits", note(strings.Repeat("D", 121)), markdown.TitleTooLong)
+	markdown.DefaultMode = markdown.Strict
 
 	fmt.Println("✔ ALL Constraints Tests Passed")
 }
 
-func testParse(name, content string, expectSuccess bool) {
+// testParse fails unless the note is rejected with exactly the expected codes (none = valid).
+func testParse(name, content string, expect ...markdown.Code) {
 	filename := "test_" + name + ".md"
 	os.WriteFile(filename, []byte(content), 0644)
 	defer os.Remove(filename)
 
-	_, err := markdown.ParseFile(filename)
-	if expectSuccess && err != nil {
-		fmt.Printf("❌ %s failed unexpectedly: %v\n", name, err)
+	_, report, err := markdown.ValidateFile(filename)
+	if err != nil {
+		fmt.Printf("❌ %s: %v\n", name, err)
 		os.Exit(1)
 	}
-	if !expectSuccess && err == nil {
-		fmt.Printf("❌ %s succeeded unexpectedly (expected failure)\n", name)
+
+	var got []markdown.Code
+	for _, v := range report.Violations {
+		if v.Severity == markdown.SeverityError {
+			got = append(got, v.Code)
+		}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(expect) {
+		fmt.Printf("❌ %s: expected %v, got %v\n%s", name, expect, got, report.String())
 		os.Exit(1)
 	}
-	if !expectSuccess {
-		fmt.Printf("✔ %s rejected as expected: %v\n", name, err)
+	if len(expect) == 0 {
+		fmt.Printf("✔ %s accepted\n", name)
+		return
 	}
+	fmt.Printf("✔ %s rejected as expected:\n%s", name, report.String())
 }
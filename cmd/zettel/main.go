@@ -0,0 +1,129 @@
+// zettel is the single entry point: the Telegram bot plus the commands to
+// maintain a vault from the terminal.
+//
+//	zettel serve                      bot + watcher + periodic sync
+//	zettel sync                       one-shot index, prints a summary
+//	zettel validate <path...>         validate notes (exit 1 on errors)
+//	zettel new [-c category] <title>  create a note from the template
+//	zettel rebuild                    reindex from scratch
+//	zettel stats                      index statistics
+//	zettel doctor                     integrity report (exit 1 on issues)
+//	zettel export [-o file]           notes and links as JSON
+//
+// Every command reads zettel.toml / env (see zettel.example.toml); -config,
+// -root and -db override them.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/eliseohh/zettelcornelbot/internal/config"
+	"github.com/eliseohh/zettelcornelbot/internal/index"
+	"github.com/eliseohh/zettelcornelbot/internal/versioning"
+)
+
+type command struct {
+	run     func(args []string)
+	summary string
+}
+
+var commands = map[string]command{
+	"serve":    {runServe, "run the Telegram bot, watcher and periodic sync"},
+	"sync":     {runSync, "index the vault once and print a summary"},
+	"validate": {runValidate, "validate notes or folders (exit 1 on errors)"},
+	"new":      {runNew, "create a note: new [-c category] [-notas text] <title...>"},
+	"rebuild":  {runRebuild, "drop derived tables and reindex the vault"},
+	"stats":    {runStats, "print index statistics"},
+	"doctor":   {runDoctor, "print the integrity report (exit 1 on issues)"},
+	"export":   {runExport, "write notes, Tipo, links and cues as JSON"},
+}
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		if os.Args[1] != "help" && os.Args[1] != "-h" && os.Args[1] != "--help" {
+			fmt.Fprintf(os.Stderr, "zettel: unknown command %q\n\n", os.Args[1])
+		}
+		usage()
+		os.Exit(2)
+	}
+	cmd.run(os.Args[2:])
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: zettel <command> [flags] [args]\n\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-9s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun 'zettel <command> -h' for its flags.")
+}
+
+// flags registers the flags every command shares. The returned function parses
+// args and loads the configuration (file < env < flags), exiting on errors.
+func flags(name string) (*flag.FlagSet, func(args []string) *config.Config) {
+	set := flag.NewFlagSet("zettel "+name, flag.ExitOnError)
+	configPath := set.String("config", os.Getenv("ZETTEL_CONFIG"), "config file (default ./zettel.toml if present)")
+	root := set.String("root", "", "vault root (overrides config / $ZETTEL_ROOT)")
+	dbPath := set.String("db", "", "SQLite index path (overrides config / $ZETTEL_DB)")
+
+	return set, func(args []string) *config.Config {
+		set.Parse(args)
+		if *root != "" {
+			os.Setenv("ZETTEL_ROOT", *root)
+		}
+		if *dbPath != "" {
+			os.Setenv("ZETTEL_DB", *dbPath)
+		}
+		conf, err := config.Load(*configPath)
+		if err != nil {
+			log.Fatalf("zettel %s: %v", name, err)
+		}
+		if err := conf.Apply(); err != nil {
+			log.Fatalf("zettel %s: %v", name, err)
+		}
+		return conf
+	}
+}
+
+// openIndex opens and migrates the index. Progress goes to out.
+func openIndex(name string, conf *config.Config, out io.Writer) (*index.DB, *index.Indexer) {
+	db, err := index.NewDB(conf.Index.DB)
+	if err != nil {
+		log.Fatalf("zettel %s: %v", name, err)
+	}
+	if err := db.Migrate(); err != nil {
+		db.Close()
+		log.Fatalf("zettel %s: schema migration failed: %v", name, err)
+	}
+	idx := index.NewIndexer(db)
+	idx.Workers = conf.Index.Workers
+	idx.Out = out
+	return db, idx
+}
+
+// openRepo returns the vault's git repository when versioning is on (nil otherwise).
+func openRepo(name string, conf *config.Config) *versioning.Repo {
+	if !conf.Vault.Git {
+		return nil
+	}
+	repo, err := versioning.Open(conf.Vault.Root)
+	if err != nil {
+		log.Fatalf("zettel %s: versioning init failed: %v", name, err)
+	}
+	return repo
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/eliseohh/zettelcornelbot/internal/bot"
+	"github.com/eliseohh/zettelcornelbot/internal/index"
+	"github.com/eliseohh/zettelcornelbot/internal/versioning"
+)
+
+func runServe(args []string) {
+	set, load := flags("serve")
+	set.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: zettel serve [flags]\n\nRuns the bot (or only the indexer without TELEGRAM_TOKEN).")
+		set.PrintDefaults()
+	}
+	conf := load(args)
+
+	fmt.Println("ZettelCornelBot: Sistema Cognitivo Local")
+	if conf.Path != "" {
+		fmt.Printf("Config: %s\n", conf.Path)
+	}
+
+	token := conf.Telegram.Token
+	if token == "" {
+		fmt.Println("⚠ No TELEGRAM_TOKEN found. Bot will not start.")
+	}
+	rootDir := conf.Vault.Root
+
+	// 1. Index (pending migrations are applied on open)
+	db, idx := openIndex("serve", conf, os.Stdout)
+	defer db.Close()
+
+	// 2. Optional git versioning: bot writes and externally changed batches become commits
+	repo := openRepo("serve", conf)
+	if repo != nil {
+		idx.OnChange = func(paths []string) {
+			if err := repo.Commit(versioning.Message{Command: "sync"}, paths...); err != nil {
+				log.Printf("Versioning sync batch failed: %v", err)
+			}
+		}
+	}
+
+	// 3. Model backend; embeddings are computed in the background after each sync
+	ai, err := conf.Backend()
+	if err != nil {
+		log.Fatalf("AI backend init failed: %v", err)
+	}
+	fmt.Printf("AI backend: %s (%s)\n", conf.AI.Backend, conf.AI.Model)
+	var embedder *index.Embedder
+	if conf.AI.Embeddings {
+		embedder = index.NewEmbedder(db, ai, conf.EmbeddingKey())
+		idx.Embedder = embedder
+		embedder.Start()
+		defer embedder.Close()
+	}
+
+	// 4. Initial Sync
+	fmt.Printf("Syncing %s...\n", rootDir)
+	if err := idx.Sync(rootDir); err != nil {
+		log.Printf("⚠ Initial sync failed: %v", err)
+	}
+
+	// 5. Watch the vault for real-time reindexing
+	watcher, err := index.NewWatcher(idx, rootDir, index.DefaultDebounce)
+	if err != nil {
+		log.Printf("⚠ Watcher unavailable, relying on periodic sync: %v", err)
+	} else {
+		watcher.Start()
+		defer watcher.Close()
+	}
+
+	// 6. Periodic full Sync (consistency check for anything the watcher missed)
+	go func() {
+		ticker := time.NewTicker(conf.Index.SyncInterval)
+		for range ticker.C {
+			if err := idx.Sync(rootDir); err != nil {
+				log.Printf("Sync error: %v", err)
+			}
+		}
+	}()
+
+	// 7. Start Bot
+	if token == "" {
+		// Just run as indexer/watcher if no bot
+		fmt.Println("Running in Indexer-Only mode.")
+		select {}
+	}
+
+	cfg, err := bot.NewConfig(conf)
+	if err != nil {
+		log.Fatalf("Invalid bot config: %v", err)
+	}
+	cfg.Git = repo
+
+	cfg.Embeddings = embedder
+
+	b, err := bot.New(cfg, db, idx, ai)
+	if err != nil {
+		log.Fatalf("Bot init failed: %v", err)
+	}
+
+	fmt.Println("🤖 Bot Online. Listening...")
+	b.Start()
+}
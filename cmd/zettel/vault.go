@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eliseohh/zettelcornelbot/internal/index"
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+	"github.com/eliseohh/zettelcornelbot/internal/vault"
+	"github.com/eliseohh/zettelcornelbot/internal/versioning"
+)
+
+func runSync(args []string) {
+	set, load := flags("sync")
+	verbose := set.Bool("v", false, "log indexer progress")
+	conf := load(args)
+
+	out := io.Discard
+	if *verbose {
+		out = os.Stderr
+	}
+	db, idx := openIndex("sync", conf, out)
+	defer db.Close()
+
+	repo := openRepo("sync", conf)
+	changed := 0
+	idx.OnChange = func(paths []string) {
+		changed = len(paths)
+		if repo != nil {
+			if err := repo.Commit(versioning.Message{Command: "sync"}, paths...); err != nil {
+				log.Printf("zettel sync: versioning failed: %v", err)
+			}
+		}
+	}
+
+	start := time.Now()
+	if err := idx.Sync(conf.Vault.Root); err != nil {
+		log.Fatalf("zettel sync: %v", err)
+	}
+	stats, err := db.Stats(time.Now())
+	if err != nil {
+		log.Fatalf("zettel sync: %v", err)
+	}
+	fmt.Printf("✅ Synced %s in %s: %d notes, %d links, %d changed, %d parse error(s)\n",
+		conf.Vault.Root, time.Since(start).Round(time.Millisecond), stats.Notes, stats.Links, changed, stats.ParseErrors)
+}
+
+func runRebuild(args []string) {
+	_, load := flags("rebuild")
+	conf := load(args)
+
+	db, idx := openIndex("rebuild", conf, io.Discard)
+	defer db.Close()
+	if err := idx.Rebuild(conf.Vault.Root); err != nil {
+		log.Fatalf("zettel rebuild: %v", err)
+	}
+	stats, err := db.Stats(time.Now())
+	if err != nil {
+		log.Fatalf("zettel rebuild: %v", err)
+	}
+	fmt.Printf("✅ Index rebuilt: %d notes (schema v%d)\n", stats.Notes, stats.SchemaVersion)
+}
+
+func runStats(args []string) {
+	set, load := flags("stats")
+	noSync := set.Bool("no-sync", false, "report the index as is, without syncing first")
+	conf := load(args)
+
+	db, idx := openIndex("stats", conf, io.Discard)
+	defer db.Close()
+	if !*noSync {
+		if err := idx.Sync(conf.Vault.Root); err != nil {
+			log.Fatalf("zettel stats: %v", err)
+		}
+	}
+	stats, err := db.Stats(time.Now())
+	if err != nil {
+		log.Fatalf("zettel stats: %v", err)
+	}
+	fmt.Print(stats.String())
+}
+
+func runDoctor(args []string) {
+	set, load := flags("doctor")
+	verbose := set.Bool("v", false, "log indexer progress to stderr")
+	conf := load(args)
+
+	// stdout carries only the report
+	out := io.Discard
+	if *verbose {
+		out = os.Stderr
+	}
+	db, idx := openIndex("doctor", conf, out)
+	defer db.Close()
+	if err := idx.Sync(conf.Vault.Root); err != nil {
+		log.Fatalf("zettel doctor: sync failed: %v", err)
+	}
+
+	report, err := db.Doctor(index.DefaultLayout)
+	if err != nil {
+		log.Fatalf("zettel doctor: %v", err)
+	}
+	fmt.Print(report.String())
+	if !report.Clean() {
+		db.Close()
+		os.Exit(1)
+	}
+}
+
+func runExport(args []string) {
+	set, load := flags("export")
+	output := set.String("o", "", "output file (default stdout)")
+	conf := load(args)
+
+	db, idx := openIndex("export", conf, io.Discard)
+	defer db.Close()
+	if err := idx.Sync(conf.Vault.Root); err != nil {
+		log.Fatalf("zettel export: %v", err)
+	}
+	notes, err := db.Export()
+	if err != nil {
+		log.Fatalf("zettel export: %v", err)
+	}
+	if notes == nil {
+		notes = []index.ExportedNote{}
+	}
+
+	data, err := json.MarshalIndent(notes, "", "  ")
+	if err != nil {
+		log.Fatalf("zettel export: %v", err)
+	}
+	data = append(data, '\n')
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		log.Fatalf("zettel export: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "✅ Exported %d notes to %s\n", len(notes), *output)
+}
+
+// runValidate prints every violation as path:pos severity Code: message.
+func runValidate(args []string) {
+	set, load := flags("validate")
+	set.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: zettel validate [flags] <file-or-dir...>")
+		set.PrintDefaults()
+	}
+	load(args)
+	if set.NArg() == 0 {
+		set.Usage()
+		os.Exit(2)
+	}
+
+	var files []string
+	for _, arg := range set.Args() {
+		info, err := os.Stat(arg)
+		if err != nil {
+			log.Fatalf("zettel validate: %v", err)
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() && strings.HasPrefix(d.Name(), ".") && path != arg {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && strings.HasSuffix(d.Name(), ".md") {
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
+
+	failed := 0
+	for _, path := range files {
+		_, report, err := markdown.ValidateFile(path)
+		if err != nil {
+			log.Fatalf("zettel validate: %v", err)
+		}
+		for _, v := range report.Violations {
+			fmt.Printf("%s:%s\n", path, v)
+		}
+		if report.HasErrors() {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("❌ %d of %d note(s) invalid\n", failed, len(files))
+		os.Exit(1)
+	}
+	fmt.Printf("✅ %d note(s) valid\n", len(files))
+}
+
+func runNew(args []string) {
+	set, load := flags("new")
+	category := set.String("c", "idea", "category (Tipo): "+strings.Join(markdown.Tipos, "|"))
+	notas := set.String("notas", "", "initial content of ## Notas")
+	set.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: zettel new [flags] <title...>")
+		set.PrintDefaults()
+	}
+	conf := load(args)
+	title := strings.Join(set.Args(), " ")
+	if title == "" {
+		set.Usage()
+		os.Exit(2)
+	}
+
+	// The category becomes a folder: lenient mode would only warn about it
+	if !markdown.IsTipo(*category) {
+		log.Fatalf("zettel new: unknown category %q (want %s)", *category, strings.Join(markdown.Tipos, "|"))
+	}
+
+	now := time.Now()
+	rel := vault.NotePath(*category, title, now)
+	path := filepath.Join(conf.Vault.Root, rel)
+	content := vault.NewNote(title, *category, *notas, now)
+	if _, err := markdown.Parse(path, content); err != nil {
+		var report *markdown.ValidationReport
+		if errors.As(err, &report) {
+			log.Fatalf("zettel new: the note would be invalid:\n%s", report.String())
+		}
+		log.Fatalf("zettel new: %v", err)
+	}
+
+	db, idx := openIndex("new", conf, io.Discard)
+	defer db.Close()
+	if err := vault.New(conf.Vault.Root, db).Create(path, content); err != nil {
+		log.Fatalf("zettel new: %s: %v", rel, err)
+	}
+	if repo := openRepo("new", conf); repo != nil {
+		msg := versioning.Message{Command: "new", NoteID: strings.TrimSuffix(filepath.Base(rel), ".md")}
+		if err := repo.Commit(msg, rel); err != nil {
+			log.Printf("zettel new: versioning failed: %v", err)
+		}
+	}
+	if err := idx.SyncPaths(conf.Vault.Root, []string{rel}); err != nil {
+		log.Printf("zettel new: reindex failed: %v", err)
+	}
+	fmt.Println(path)
+}
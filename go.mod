@@ -3,6 +3,10 @@ module github.com/eliseohh/zettelcornelbot
 go 1.25.5
 
 require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/fsnotify/fsnotify v1.9.0
 	github.com/mattn/go-sqlite3 v1.14.33
 	gopkg.in/telebot.v3 v3.3.8
 )
+
+require golang.org/x/sys v0.13.0 // indirect
@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/eliseohh/zettelcornelbot/internal/config"
+	tele "gopkg.in/telebot.v3"
+)
+
+// Endpoints each non-owner role may use (owners can do everything). Commands are
+// keyed like "/note links" (command plus /note subcommand); callback buttons by their Unique.
+var rolePermissions = map[config.Role]map[string]bool{
+	config.RoleReader:   {"/status": true, "/find": true, "/find~": true, "/similar": true, "/note links": true},
+	config.RoleReviewer: {"/review": true, reviewBtn.Unique: true},
+}
+
+func allows(role config.Role, endpoint string) bool {
+	return role == config.RoleOwner || rolePermissions[role][endpoint]
+}
+
+// authorize is the global middleware: every update is checked against the
+// allowlist before reaching a handler. Strangers get no reply at all.
+func (b *Bot) authorize(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var userID int64
+		username := ""
+		if u := c.Sender(); u != nil {
+			userID, username = u.ID, u.Username
+		}
+		var chatID int64
+		if ch := c.Chat(); ch != nil {
+			chatID = ch.ID
+		}
+
+		endpoint := updateEndpoint(c)
+		role := b.cfg.Access.Role(userID, chatID)
+		if allows(role, endpoint) {
+			return next(c)
+		}
+
+		b.audit(userID, chatID, username, "denied", endpoint, role)
+		if role == "" {
+			return nil
+		}
+		if c.Callback() != nil {
+			return c.Respond(&tele.CallbackResponse{Text: "⛔ Not allowed for your role"})
+		}
+		return c.Send(fmt.Sprintf("⛔ Forbidden: role %s cannot use %s", role, endpoint))
+	}
+}
+
+// updateEndpoint names what an update asks for: a button Unique, "/cmd",
+// "/note <action>", or "text" for free text.
+func updateEndpoint(c tele.Context) string {
+	if cb := c.Callback(); cb != nil {
+		return cb.Unique
+	}
+	m := c.Message()
+	if m == nil {
+		return ""
+	}
+	fields := strings.Fields(m.Text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "text"
+	}
+	cmd, _, _ := strings.Cut(strings.ToLower(fields[0]), "@") // "/note@ZettelBot"
+	if cmd == "/note" && len(fields) > 1 {
+		cmd += " " + strings.ToLower(fields[1])
+	}
+	return cmd
+}
+
+// audit records a security event. Failures are logged only.
+func (b *Bot) audit(userID, chatID int64, username, event, action string, role config.Role) {
+	log.Printf("🔒 %s: user %d (@%s) chat %d → %s", event, userID, username, chatID, action)
+	_, err := b.db.Exec(`INSERT INTO audit_log (user_id, chat_id, username, event, action, role, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, userID, chatID, username, event, action, string(role), time.Now().Unix())
+	if err != nil {
+		log.Printf("⚠️ Audit log failed: %v", err)
+	}
+}
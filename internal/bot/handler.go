@@ -1,31 +1,76 @@
 package bot
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"html"
+	"log"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"time"
 
+	"github.com/eliseohh/zettelcornelbot/internal/config"
 	"github.com/eliseohh/zettelcornelbot/internal/index"
 	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+	"github.com/eliseohh/zettelcornelbot/internal/neural"
+	"github.com/eliseohh/zettelcornelbot/internal/scheduler"
+	"github.com/eliseohh/zettelcornelbot/internal/vault"
+	"github.com/eliseohh/zettelcornelbot/internal/versioning"
 	tele "gopkg.in/telebot.v3"
 )
 
 type Bot struct {
-	api *tele.Bot
-	db  *index.DB
-	cfg Config
+	api   *tele.Bot
+	out   sender
+	db    *index.DB
+	idx   *index.Indexer
+	sched *scheduler.Scheduler
+	vault *vault.Vault   // Every note write goes through it
+	ai    neural.Backend // nil = /ai disabled
+	cfg   Config
 }
 
 type Config struct {
 	Token    string
 	RootDir  string
 	InboxDir string
+
+	DailyPurgeHour int            // Local hour (0-23) when unpromoted /daily entries are dropped
+	Timezone       *time.Location // Default for chats without /tz (nil = time.Local)
+
+	Git        *versioning.Repo // Commits every bot write when set; nil = versioning off
+	Embeddings *index.Embedder  // Vector search for /similar and /find~; nil = off
+
+	Access config.ACL // Who may talk to the bot; required
+}
+
+// NewConfig builds the bot settings from the loaded config. Git and Embeddings
+// are left for the caller, which shares them with the indexer.
+func NewConfig(c *config.Config) (Config, error) {
+	acl, err := c.ACL()
+	if err != nil {
+		return Config{}, err
+	}
+	loc, err := c.Location()
+	if err != nil {
+		return Config{}, err
+	}
+	return Config{
+		Token:          c.Telegram.Token,
+		RootDir:        c.Vault.Root,
+		InboxDir:       c.Vault.Root,
+		DailyPurgeHour: c.Scheduler.DailyPurgeHour,
+		Timezone:       loc,
+		Access:         acl,
+	}, nil
 }
 
-func New(cfg Config, db *index.DB) (*Bot, error) {
+// New wires the bot. ai is the model backend behind /ai (nil disables it).
+func New(cfg Config, db *index.DB, idx *index.Indexer, ai neural.Backend) (*Bot, error) {
+	if len(cfg.Access) == 0 {
+		return nil, errors.New("empty allowlist: the bot would answer anyone (set ZETTEL_ALLOW or ZETTEL_ALLOW_FILE)")
+	}
+
 	pref := tele.Settings{
 		Token:  cfg.Token,
 		Poller: &tele.LongPoller{Timeout: 10 * time.Second},
@@ -36,27 +81,58 @@ func New(cfg Config, db *index.DB) (*Bot, error) {
 		return nil, err
 	}
 
-	bot := &Bot{api: b, db: db, cfg: cfg}
+	bot := &Bot{
+		api:   b,
+		out:   b,
+		db:    db,
+		idx:   idx,
+		sched: scheduler.New(db, scheduler.SystemClock{}, cfg.Timezone),
+		vault: vault.New(cfg.RootDir, db),
+		ai:    ai,
+		cfg:   cfg,
+	}
 	bot.register()
 	return bot, nil
 }
 
 func (b *Bot) Start() {
 	fmt.Printf("Bot started: %s\n", b.api.Me.Username)
+	b.sched.Start(scheduler.DefaultTick)
+	defer b.sched.Stop()
 	b.api.Start()
 }
 
 func (b *Bot) register() {
+	// Allowlist first: middleware only wraps handlers registered after it
+	b.api.Use(b.authorize)
+
 	// Root Commands
 	b.api.Handle("/note", b.handleNote)
 	b.api.Handle("/cue", b.handleCue)
 
+	// Search
+	b.registerFind()
+
+	// Active Recall
+	b.registerReview()
+
+	// Timed messages
+	b.registerSchedule()
+
+	// Focus accountability
+	b.registerWork()
+	b.registerDaily()
+	b.registerHistory()
+
 	// Legacy/Utility (kept for status check)
 	b.api.Handle("/status", b.handleStatus)
 
 	// Catch-all for Text to Strict Reject
 	b.api.Handle(tele.OnText, func(c tele.Context) error {
-		// Checks...
+		// "/find~" is not a valid Telegram command name, so it arrives as text
+		if query, ok := strings.CutPrefix(c.Message().Text, semanticFindCmd); ok {
+			return b.handleSemanticFind(c, strings.TrimSpace(query))
+		}
 		return c.Send("⛔ Error: Texto libre prohibido. Use comandos atómicos.")
 	})
 
@@ -70,7 +146,7 @@ func (b *Bot) handleNote(c tele.Context) error {
 	args := strings.Fields(payload)
 
 	if len(args) < 1 {
-		return c.Send("Usage: /note [create|validate|link] ...")
+		return c.Send("Usage: /note [create|validate|link|links|doctor|diff] ...")
 	}
 
 	action := strings.ToLower(args[0])
@@ -86,9 +162,7 @@ func (b *Bot) handleNote(c tele.Context) error {
 		var titleStart = 1
 
 		firstArg := strings.ToLower(args[1])
-		knownCategories := map[string]bool{"idea": true, "estudio": true, "libro": true, "tarea": true}
-
-		if knownCategories[firstArg] {
+		if isCategory(firstArg) {
 			category = firstArg
 			titleStart = 2
 			if len(args) < 3 {
@@ -113,6 +187,23 @@ func (b *Bot) handleNote(c tele.Context) error {
 		}
 		return b.noteLink(c, args[1], args[2])
 
+	case "links":
+		// /note links <ID>
+		if len(args) < 2 {
+			return c.Send("Usage: /note links <ID>")
+		}
+		return b.noteLinks(c, args[1])
+
+	case "doctor":
+		return b.noteDoctor(c)
+
+	case "diff":
+		// /note diff <ID>
+		if len(args) < 2 {
+			return c.Send("Usage: /note diff <ID>")
+		}
+		return b.noteDiff(c, args[1])
+
 	default:
 		return c.Send(fmt.Sprintf("Unknown action: %s", action))
 	}
@@ -144,153 +235,311 @@ func (b *Bot) handleCue(c tele.Context) error {
 // -- Implementations --
 
 func (b *Bot) noteCreate(c tele.Context, title, category string) error {
-	// Generate Filename: YYYYMMDD-kebab-title.md
-	dateStr := time.Now().Format("20060102")
-	kebab := toKebab(title)
-	filename := fmt.Sprintf("%s-%s.md", dateStr, kebab)
-
-	// Determine Path (Folder)
-	var targetDir string
-	if category == "idea" {
-		targetDir = b.cfg.RootDir
-	} else {
-		targetDir = filepath.Join(b.cfg.RootDir, category)
-		if err := os.MkdirAll(targetDir, 0755); err != nil {
-			return c.Send(fmt.Sprintf("FS Error: %v", err))
-		}
-	}
-	path := filepath.Join(targetDir, filename)
-
-	// Ensure atomic: Check if exists
-	if _, err := os.Stat(path); err == nil {
+	relPath, err := b.createNote(c.Chat().ID, title, category, "")
+	if err == errNoteExists {
 		return c.Send("⛔ Error: Note already exists.")
 	}
+	if err != nil {
+		return sendWriteErr(c, err)
+	}
+	return c.Send(fmt.Sprintf("✅ Created: `%s`", relPath))
+}
 
-	content := fmt.Sprintf(`# %s
-Fecha: %s
-Tipo: %s
-
-## Notas
-
+var errNoteExists = vault.ErrExists
 
-## Cues
+// createNote writes a new note from the template and returns its vault-relative path.
+// notas optionally pre-fills the ## Notas section. Logged as a "create" op of chatID.
+func (b *Bot) createNote(chatID int64, title, category, notas string) (string, error) {
+	now := time.Now()
+	relPath := vault.NotePath(category, title, now)
+	if err := b.writeNote(chatID, relPath, vault.NewNote(title, category, notas, now)); err != nil {
+		return "", err
+	}
+	return relPath, nil
+}
 
+// writeNote creates the note at relPath with content, which must validate.
+// Logged as a "create" op of chatID.
+func (b *Bot) writeNote(chatID int64, relPath string, content []byte) error {
+	path := filepath.Join(b.cfg.RootDir, relPath)
+	if _, err := markdown.Parse(path, content); err != nil {
+		return err
+	}
 
-## Resumen
+	// Fails with errNoteExists if the path is taken
+	if err := b.vault.Create(path, content); err != nil {
+		return err
+	}
+	b.recordOp(chatID, "create", path, nil, content)
+	b.version(chatID, "create", path)
+	b.reindex(path)
+	return nil
+}
 
+// mutate applies edit to the note at path through the document model and writes
+// the result only if it still validates. Nothing is written when edit fails or
+// the file changed on disk since it was indexed. Successful writes are logged
+// as op for chatID (see /undo).
+func (b *Bot) mutate(chatID int64, op, path string, edit func(*markdown.Document) error) error {
+	var before, after []byte
+	err := b.vault.Update(path, func(old []byte) ([]byte, error) {
+		doc := markdown.ParseDocument(old)
+		if err := edit(doc); err != nil {
+			return nil, err
+		}
+		if _, err := doc.Validate(path); err != nil {
+			return nil, err
+		}
+		before, after = old, doc.Render()
+		return after, nil
+	})
+	if err == vault.ErrConflict {
+		// Catch the index up with the external edit so a retry starts from it
+		b.reindex(path)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	b.recordOp(chatID, op, path, before, after)
+	b.version(chatID, op, path)
+	b.reindex(path)
+	return nil
+}
 
-## Enlaces
+// sendWriteErr reports a failed create/mutate; validation failures list every violation.
+func sendWriteErr(c tele.Context, err error) error {
+	var report *markdown.ValidationReport
+	if errors.As(err, &report) {
+		return c.Send("⛔ Rejected: the note would be invalid.\n" + formatViolations(report))
+	}
+	if err == vault.ErrConflict {
+		return c.Send("⛔ Conflict: the note changed on disk since it was read. Index refreshed, try again.")
+	}
+	return c.Send(fmt.Sprintf("⛔ Error: %v", err))
+}
 
-`, title, time.Now().Format("2006-01-02"), category)
+// resolveErr is a resolvePath failure for id, inside a write helper.
+type resolveErr struct {
+	id  string
+	err error
+}
 
-	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
-		return c.Send(fmt.Sprintf("FS Error: %v", err))
-	}
+func (e *resolveErr) Error() string { return e.id + ": " + e.err.Error() }
+func (e *resolveErr) Unwrap() error { return e.err }
 
-	relPath := filename
-	if category != "idea" {
-		relPath = filepath.Join(category, filename)
+// sendNoteErr reports a write helper failure: resolve errors as sendResolveErr,
+// the rest as sendWriteErr.
+func sendNoteErr(c tele.Context, err error) error {
+	var r *resolveErr
+	if errors.As(err, &r) {
+		return sendResolveErr(c, r.id, r.err)
 	}
-	return c.Send(fmt.Sprintf("✅ Created: `%s`", relPath))
+	return sendWriteErr(c, err)
 }
 
 func (b *Bot) noteValidate(c tele.Context, id string) error {
 	path, err := b.resolvePath(id)
 	if err != nil {
-		return c.Send(fmt.Sprintf("🔍 Not Found: %s", id))
+		return sendResolveErr(c, id, err)
 	}
 
-	_, err = markdown.ParseFile(path)
+	_, report, err := markdown.ValidateFile(path)
 	if err != nil {
-		return c.Send(fmt.Sprintf("❌ Invalid: %v", err))
+		return c.Send(fmt.Sprintf("FS Error: %v", err))
+	}
+	if report.HasErrors() {
+		return c.Send(fmt.Sprintf("❌ Invalid: `%s`\n%s", id, formatViolations(report)))
+	}
+	if len(report.Violations) > 0 {
+		return c.Send(fmt.Sprintf("✅ Valid: `%s`\n%s", id, formatViolations(report)))
 	}
 	return c.Send(fmt.Sprintf("✅ Valid: `%s`", id))
 }
 
+// formatViolations lists a report one violation per line: "❌ L5:3-5:40 CueTooLong: ...".
+func formatViolations(r *markdown.ValidationReport) string {
+	sb := strings.Builder{}
+	for _, v := range r.Violations {
+		mark := "⚠"
+		if v.Severity == markdown.SeverityError {
+			mark = "❌"
+		}
+		sb.WriteString(fmt.Sprintf("%s L%s %s: %s\n", mark, v.Pos(), v.Code, v.Message))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
 func (b *Bot) noteLink(c tele.Context, srcID, tgtID string) error {
-	srcPath, err := b.resolvePath(srcID)
+	msg, err := b.linkNotes(c.Chat().ID, srcID, tgtID)
 	if err != nil {
-		return c.Send(fmt.Sprintf("🔍 Not Found Source: %s", srcID))
+		return sendNoteErr(c, err)
 	}
+	return c.Send(msg)
+}
 
-	// Atomic Append to "## Enlaces" section
-	// Strategy: Read file, find line "## Enlaces", append "- [[tgtID]]" after it.
-	content, err := os.ReadFile(srcPath)
+// linkNotes adds [[tgtID]] to srcID's ## Enlaces and returns the reply.
+func (b *Bot) linkNotes(chatID int64, srcID, tgtID string) (string, error) {
+	srcPath, err := b.resolvePath(srcID)
 	if err != nil {
+		return "", &resolveErr{srcID, err}
+	}
+
+	// Target may not exist yet (dangling links are allowed), but must not be ambiguous.
+	dangling := false
+	if _, err := b.resolvePath(tgtID); err != nil {
+		if err != errNotFound {
+			return "", &resolveErr{tgtID, err}
+		}
+		dangling = true
+	}
+
+	added := false
+	err = b.mutate(chatID, "link", srcPath, func(doc *markdown.Document) error {
+		added, err = doc.AddLink(tgtID)
 		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if !added {
+		return fmt.Sprintf("🔗 Already linked: %s -> %s", srcID, tgtID), nil
+	}
+
+	if dangling {
+		return fmt.Sprintf("🔗 Linked: %s -> %s (⚠ target not found yet)", srcID, tgtID), nil
+	}
+	return fmt.Sprintf("🔗 Linked: %s -> %s", srcID, tgtID), nil
+}
+
+func (b *Bot) noteLinks(c tele.Context, id string) error {
+	if _, err := b.resolvePath(id); err != nil {
+		return sendResolveErr(c, id, err)
 	}
 
-	lines := strings.Split(string(content), "\n")
-	var newLines []string
-	foundSection := false
-	inserted := false
+	out, err := b.db.Outlinks(id)
+	if err != nil {
+		return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+	}
+	back, err := b.db.Backlinks(id)
+	if err != nil {
+		return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+	}
 
-	for _, line := range lines {
-		newLines = append(newLines, line)
-		if strings.TrimSpace(line) == "## Enlaces" {
-			foundSection = true
-			if !inserted {
-				newLines = append(newLines, fmt.Sprintf("- [[%s]]", tgtID))
-				inserted = true
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("🔗 Links: `%s`\n", id))
+	writeLinks := func(header string, links []index.Link) {
+		sb.WriteString(fmt.Sprintf("\n%s (%d)\n", header, len(links)))
+		for i, l := range links {
+			branch := " ├── "
+			if i == len(links)-1 {
+				branch = " └── "
 			}
+			label := l.Title
+			if l.Dangling {
+				label = "⚠ dangling"
+			}
+			sb.WriteString(fmt.Sprintf("%s`%s` %s\n", branch, l.ID, label))
 		}
 	}
+	writeLinks("→ Outgoing", out)
+	writeLinks("← Backlinks", back)
 
-	if !foundSection {
-		// Section missing? Strict format says it must exist.
-		// If missing, append it? Or error?
-		// Verification check says validation enforces it. So it should exist if valid.
-		return c.Send("⛔ Error: missing '## Enlaces' section in source.")
-	}
+	return c.Send(sb.String())
+}
+
+// maxMessageChars keeps replies under Telegram's 4096-char message limit.
+const maxMessageChars = 4000
 
-	if err := os.WriteFile(srcPath, []byte(strings.Join(newLines, "\n")), 0644); err != nil {
-		return nil // err
+func (b *Bot) noteDoctor(c tele.Context) error {
+	report, err := b.db.Doctor(index.DefaultLayout)
+	if err != nil {
+		return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+	}
+	if report.Clean() {
+		return c.Send("✅ Doctor: no integrity issues.")
 	}
 
-	return c.Send(fmt.Sprintf("🔗 Linked: %s -> %s", srcID, tgtID))
+	text := truncateLines("🩺 Doctor\n\n"+report.String(), maxMessageChars, "… (truncated, run `zettel doctor` for the full report)")
+	return c.Send(text)
 }
 
-func (b *Bot) cueAdd(c tele.Context, id, question string) error {
-	// Validation first
-	if !strings.HasSuffix(strings.TrimSpace(question), "?") {
-		return c.Send("⛔ Error: Cue must end with '?'")
+// truncateLines cuts text to at most max bytes at a line boundary (so no entry
+// or rune is split), appending marker when something was dropped.
+func truncateLines(text string, max int, marker string) string {
+	if len(text) <= max {
+		return text
 	}
+	cut := strings.LastIndex(text[:max-len(marker)-1], "\n")
+	if cut < 0 {
+		cut = 0
+	}
+	return text[:cut] + "\n" + marker
+}
 
+func (b *Bot) noteDiff(c tele.Context, id string) error {
+	if b.cfg.Git == nil {
+		return c.Send("⛔ Versioning disabled (set ZETTEL_GIT=1).")
+	}
 	path, err := b.resolvePath(id)
 	if err != nil {
-		return c.Send(fmt.Sprintf("🔍 Not Found: %s", id))
+		return sendResolveErr(c, id, err)
+	}
+	rel, err := filepath.Rel(b.cfg.RootDir, path)
+	if err != nil {
+		return c.Send(fmt.Sprintf("⛔ Error: %v", err))
 	}
 
-	content, err := os.ReadFile(path)
+	out, err := b.cfg.Git.LastChange(rel)
+	if err == versioning.ErrNoHistory {
+		return c.Send(fmt.Sprintf("📭 No committed history for `%s`.", id))
+	}
 	if err != nil {
-		return err
+		return c.Send(fmt.Sprintf("⛔ Git Error: %v", err))
 	}
 
-	lines := strings.Split(string(content), "\n")
-	var newLines []string
-	found := false
-	inserted := false
+	// Leave room for the <pre> wrapper and escaping growth
+	out = truncateLines(out, maxMessageChars/2, "…")
+	return c.Send("<pre>"+html.EscapeString(out)+"</pre>", &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
 
-	for _, line := range lines {
-		newLines = append(newLines, line)
-		if strings.TrimSpace(line) == "## Cues" {
-			found = true
-			if !inserted {
-				newLines = append(newLines, fmt.Sprintf("- %s", question))
-				inserted = true
-			}
-		}
+// version commits a bot write when versioning is on. Failures are logged, not surfaced:
+// the note itself was written fine.
+func (b *Bot) version(chatID int64, op, path string) {
+	if b.cfg.Git == nil {
+		return
 	}
+	rel, err := filepath.Rel(b.cfg.RootDir, path)
+	if err != nil {
+		return
+	}
+	msg := versioning.Message{Command: op, NoteID: noteID(path), ChatID: chatID}
+	if err := b.cfg.Git.Commit(msg, rel); err != nil {
+		log.Printf("Versioning %s failed: %v", rel, err)
+	}
+}
 
-	if !found {
-		return c.Send("⛔ Error: missing '## Cues' section.")
+func (b *Bot) cueAdd(c tele.Context, id, question string) error {
+	if err := b.addCue(c.Chat().ID, id, question); err != nil {
+		return sendNoteErr(c, err)
 	}
+	return c.Send("✅ Cue Added")
+}
 
-	if err := os.WriteFile(path, []byte(strings.Join(newLines, "\n")), 0644); err != nil {
-		return c.Send("Write Error")
+func (b *Bot) addCue(chatID int64, id, question string) error {
+	// Validation first: same rule as AI-proposed cues
+	if err := markdown.DefaultLimits.CheckCue(question); err != nil {
+		return fmt.Errorf("cue %w", err)
 	}
 
-	return c.Send("✅ Cue Added")
+	path, err := b.resolvePath(id)
+	if err != nil {
+		return &resolveErr{id, err}
+	}
+
+	return b.mutate(chatID, "cue", path, func(doc *markdown.Document) error {
+		return doc.AddCue(question)
+	})
 }
 
 // Helpers
@@ -324,22 +573,25 @@ func (b *Bot) handleStatus(c tele.Context) error {
 	return c.Send(sb.String(), &tele.SendOptions{ParseMode: tele.ModeMarkdown})
 }
 
-func (b *Bot) resolvePath(id string) (string, error) {
-	// ID is filename without extension?
-	// Try implicit path
-	path := filepath.Join(b.cfg.RootDir, id+".md")
-	if _, err := os.Stat(path); err == nil {
-		return path, nil
+// reindex refreshes the index for a note the bot just wrote, without waiting for the watcher.
+func (b *Bot) reindex(path string) {
+	if b.idx == nil {
+		return
+	}
+	rel, err := filepath.Rel(b.cfg.RootDir, path)
+	if err != nil {
+		return
+	}
+	if err := b.idx.SyncPaths(b.cfg.RootDir, []string{rel}); err != nil {
+		log.Printf("Reindex %s failed: %v", rel, err)
 	}
-
-	// Try explicit DB lookup if we tracked ID vs Path?
-	// Our indexer derives ID from filename.
-	// So strict mapping: ID == Basename.
-	return "", fmt.Errorf("not found")
 }
 
-func toKebab(s string) string {
-	s = strings.ToLower(s)
-	reg := regexp.MustCompile("[^a-z0-9]+")
-	return strings.Trim(reg.ReplaceAllString(s, "-"), "-")
+func isCategory(s string) bool {
+	for _, cat := range categories() {
+		if cat == s {
+			return true
+		}
+	}
+	return false
 }
@@ -1,68 +1,104 @@
 package bot
 
 import (
+	"errors"
 	"fmt"
+	"html"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/eliseohh/zettelcornelbot/internal/index"
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
 	"github.com/eliseohh/zettelcornelbot/internal/neural"
+	"github.com/eliseohh/zettelcornelbot/internal/vault"
 	tele "gopkg.in/telebot.v3"
 )
 
-// Add Neural Client to Bot struct in handler.go (Separate update needed)
-// For now, extending Bot via strict file separation might require modifying the struct definition.
-// We will assume Bot struct is updated or we pass it here.
+// Callback endpoint for every /ai proposal button (links, cues, resumen).
+// Data: "<pending action ID>".
+var aiApplyBtn = &tele.Btn{Unique: "aiapply"}
+
+const (
+	aiLinksDefault = 5
+	aiLinksMax     = 10
+	aiExcerptChars = 600 // Per candidate note in the prompt
+)
 
 func (b *Bot) registerAI() {
 	b.api.Handle("/ai", b.handleAI)
+	b.api.Handle(aiApplyBtn, b.handleAIApply)
 }
 
 func (b *Bot) handleAI(c tele.Context) error {
 	payload := c.Message().Payload
 	args := strings.Fields(payload)
 	if len(args) < 1 {
-		return c.Send("Usage: /ai [summarize|cues|draft] ...")
+		return c.Send("Usage: /ai [summarize|cues|draft|links] ...")
 	}
 
+	if b.ai == nil {
+		return c.Send("⛔ AI disabled (no backend configured).")
+	}
 	action := strings.ToLower(args[0])
 
-	// Check if Ollama is enabled/configured?
-	// We can init a client on the fly or better store in Bot.
-	// Assuming simple instantiation for now or centralized.
-	ai := neural.NewClient(os.Getenv("OLLAMA_URL"), os.Getenv("OLLAMA_MODEL"))
-
 	switch action {
 	case "summarize":
 		if len(args) < 2 {
 			return c.Send("Usage: /ai summarize <ID>")
 		}
 		id := args[1]
-		return b.aiSummarize(c, ai, id)
+		return b.aiSummarize(c, id)
 
 	case "cues":
 		if len(args) < 2 {
 			return c.Send("Usage: /ai cues <ID>")
 		}
 		id := args[1]
-		return b.aiCues(c, ai, id)
+		return b.aiCues(c, id)
 
 	case "draft":
+		// /ai draft [category] <Topic...>, same heuristic as /note create
+		if len(args) < 2 {
+			return c.Send("Usage: /ai draft [category] <Topic...>")
+		}
+		category, topicStart := "idea", 1
+		if isCategory(strings.ToLower(args[1])) {
+			category, topicStart = strings.ToLower(args[1]), 2
+			if len(args) < 3 {
+				return c.Send(fmt.Sprintf("Usage: /ai draft %s <Topic...>", category))
+			}
+		}
+		return b.aiDraft(c, strings.Join(args[topicStart:], " "), category)
+
+	case "links":
 		if len(args) < 2 {
-			return c.Send("Usage: /ai draft <Topic...>")
+			return c.Send("Usage: /ai links <ID> [N]")
 		}
-		topic := strings.Join(args[1:], " ")
-		return b.aiDraft(c, ai, topic)
+		n := aiLinksDefault
+		if len(args) > 2 {
+			v, err := strconv.Atoi(args[2])
+			if err != nil || v < 1 || v > aiLinksMax {
+				return c.Send(fmt.Sprintf("⛔ Error: N must be 1-%d", aiLinksMax))
+			}
+			n = v
+		}
+		return b.aiLinks(c, args[1], n)
 
 	default:
-		return c.Send("Unknown AI command. Permitted: summarize, cues, draft")
+		return c.Send("Unknown AI command. Permitted: summarize, cues, draft, links")
 	}
 }
 
-func (b *Bot) aiSummarize(c tele.Context, ai *neural.Client, id string) error {
-	path, err := b.resolvePath(id) // Reusing existing helper
+func (b *Bot) aiSummarize(c tele.Context, id string) error {
+	path, err := b.resolvePath(id)
 	if err != nil {
-		return c.Send(fmt.Sprintf("🔍 Not Found: %s", id))
+		return sendResolveErr(c, id, err)
 	}
+	id = strings.TrimSuffix(filepath.Base(path), ".md")
 
 	content, err := os.ReadFile(path)
 	if err != nil {
@@ -70,41 +106,248 @@ func (b *Bot) aiSummarize(c tele.Context, ai *neural.Client, id string) error {
 	}
 
 	c.Send("🧠 Thinking...")
-	summary, err := ai.Summarize(string(content))
+	limits := markdown.DefaultLimits
+	summary, err := neural.ProposeSummary(b.ai, string(content), limits)
 	if err != nil {
 		return c.Send(fmt.Sprintf("AI Error: %v", err))
 	}
+	actionID, err := b.propose(c.Chat().ID, "resumen", id, summary.Text)
+	if err != nil {
+		return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+	}
 
-	return c.Send(fmt.Sprintf("📝 **Summary Suggestion**:\n\n%s", summary), &tele.SendOptions{ParseMode: tele.ModeMarkdown})
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("📝 <b>Resumen proposal</b> for <code>%s</code> (%d/%d chars)\n\n%s\n",
+		html.EscapeString(id), utf8.RuneCountInString(summary.Text), limits.ResumenChars, html.EscapeString(summary.Text)))
+	writeFixes(&sb, summary.Fixes)
+	m := &tele.ReplyMarkup{}
+	m.Inline(m.Row(m.Data("✅ Apply (replaces ## Resumen)", aiApplyBtn.Unique, strconv.FormatInt(actionID, 10))))
+	return c.Send(sb.String(), &tele.SendOptions{ParseMode: tele.ModeHTML, ReplyMarkup: m})
 }
 
-func (b *Bot) aiCues(c tele.Context, ai *neural.Client, id string) error {
+func (b *Bot) aiCues(c tele.Context, id string) error {
 	path, err := b.resolvePath(id)
 	if err != nil {
-		return c.Send(fmt.Sprintf("🔍 Not Found: %s", id))
+		return sendResolveErr(c, id, err)
 	}
+	id = strings.TrimSuffix(filepath.Base(path), ".md")
 
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return c.Send("Read Error")
 	}
+	existing := 0
+	if s := markdown.ParseDocument(content).Section("Cues"); s != nil {
+		for _, line := range s.Lines {
+			if strings.HasPrefix(strings.TrimSpace(line), "-") {
+				existing++
+			}
+		}
+	}
+	limits := markdown.DefaultLimits
+	if existing >= limits.CuesCount {
+		return c.Send(fmt.Sprintf("⛔ Error: %s already has %d cues (max %d).", id, existing, limits.CuesCount))
+	}
 
 	c.Send("🧠 Thinking...")
-	cues, err := ai.SuggestCues(string(content))
+	proposal, err := neural.ProposeCues(b.ai, string(content), existing, limits)
 	if err != nil {
 		return c.Send(fmt.Sprintf("AI Error: %v", err))
 	}
 
-	return c.Send(fmt.Sprintf("❓ **Cue Suggestions**:\n\n%s\n\n_Use /cue add <id> <text> to apply_", cues), &tele.SendOptions{ParseMode: tele.ModeMarkdown})
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("❓ <b>Cue proposals</b> for <code>%s</code> (%d/%d used)\n", html.EscapeString(id), existing, limits.CuesCount))
+	m := &tele.ReplyMarkup{}
+	var btns []tele.Btn
+	for i, cue := range proposal.Cues {
+		actionID, err := b.propose(c.Chat().ID, "cue", id, cue)
+		if err != nil {
+			return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+		}
+		sb.WriteString(fmt.Sprintf("\n%d. %s", i+1, html.EscapeString(cue)))
+		btns = append(btns, m.Data(fmt.Sprintf("✅ %d", i+1), aiApplyBtn.Unique, strconv.FormatInt(actionID, 10)))
+	}
+	if len(btns) == 0 {
+		sb.WriteString("\nNo valid cue proposed.")
+	}
+	sb.WriteString("\n")
+	if len(proposal.Rejected) > 0 {
+		sb.WriteString("\n⚠ Rejected:")
+		for _, r := range proposal.Rejected {
+			sb.WriteString(fmt.Sprintf("\n- %s — %s", html.EscapeString(r.Text), html.EscapeString(r.Reason)))
+		}
+		sb.WriteString("\n")
+	}
+	writeFixes(&sb, proposal.Fixes)
+
+	if len(btns) == 0 {
+		return c.Send(sb.String(), &tele.SendOptions{ParseMode: tele.ModeHTML})
+	}
+	sb.WriteString("\nTap ✅ to add a cue.")
+	m.Inline(m.Split(4, btns)...)
+	return c.Send(sb.String(), &tele.SendOptions{ParseMode: tele.ModeHTML, ReplyMarkup: m})
 }
 
-func (b *Bot) aiDraft(c tele.Context, ai *neural.Client, topic string) error {
+// writeFixes notes what was done to make a proposal valid.
+func writeFixes(sb *strings.Builder, fixes []string) {
+	for _, f := range fixes {
+		sb.WriteString(fmt.Sprintf("\n<i>↻ %s</i>", html.EscapeString(f)))
+	}
+	if len(fixes) > 0 {
+		sb.WriteString("\n")
+	}
+}
+
+// aiDraft creates a new note about topic from a validated draft. The note is
+// an ordinary "create" op, so /undo removes it.
+func (b *Bot) aiDraft(c tele.Context, topic, category string) error {
 	c.Send("🧠 Drafting...")
-	draft, err := ai.Draft(topic)
+	now := time.Now()
+	known := func(id string) bool {
+		_, err := b.db.LookupPath(id)
+		return err == nil
+	}
+	draft, err := neural.ProposeDraft(b.ai, topic, category, now, known)
 	if err != nil {
 		return c.Send(fmt.Sprintf("AI Error: %v", err))
 	}
+	if vault.Kebab(draft.Title) == "" {
+		return c.Send("AI Error: the draft title has no usable characters for a filename.")
+	}
+
+	relPath := vault.NotePath(category, draft.Title, now)
+	err = b.writeNote(c.Chat().ID, relPath, draft.Content)
+	if err == errNoteExists {
+		return c.Send(fmt.Sprintf("⛔ Error: Note already exists: %s", relPath))
+	}
+	if err != nil {
+		return sendWriteErr(c, err)
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("✅ Draft created: <code>%s</code>\n<b>%s</b>\n", html.EscapeString(relPath), html.EscapeString(draft.Title)))
+	writeFixes(&sb, draft.Fixes)
+	if len(draft.Dropped) > 0 {
+		sb.WriteString(fmt.Sprintf("Dropped links to missing notes: %s\n", html.EscapeString(strings.Join(draft.Dropped, ", "))))
+	}
+	sb.WriteString("\nReview it with /note validate, or /undo to discard.")
+	return c.Send(sb.String(), &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
+
+// aiLinks proposes up to n notes to link from id: candidates are ranked by
+// embeddings, shared terms and edges, then the model justifies (or skips) each.
+func (b *Bot) aiLinks(c tele.Context, id string, n int) error {
+	path, err := b.resolvePath(id)
+	if err != nil {
+		return sendResolveErr(c, id, err)
+	}
+	id = strings.TrimSuffix(filepath.Base(path), ".md")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return c.Send("Read Error")
+	}
 
-	// Send as code block for easy copy
-	return c.Send(fmt.Sprintf("📄 **Draft Generated**:\n```markdown\n%s\n```\n_Copy and use /note create to start._", draft), &tele.SendOptions{ParseMode: tele.ModeMarkdown})
+	var similar []index.Match
+	if b.cfg.Embeddings != nil {
+		rel, _ := filepath.Rel(b.cfg.RootDir, path)
+		similar, err = b.cfg.Embeddings.SimilarTo(rel, 4*aiLinksMax)
+		if err != nil && !errors.Is(err, index.ErrNotEmbedded) {
+			return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+		}
+	}
+	suggestions, err := b.db.SuggestLinks(id, similar, n)
+	if err != nil {
+		return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+	}
+	if len(suggestions) == 0 {
+		return c.Send(fmt.Sprintf("🔗 No link suggestions for %s", id))
+	}
+
+	c.Send("🧠 Thinking...")
+	candidates := make([]neural.LinkCandidate, len(suggestions))
+	for i, s := range suggestions {
+		excerpt, _ := os.ReadFile(filepath.Join(b.cfg.RootDir, s.Path))
+		candidates[i] = neural.LinkCandidate{ID: s.ID, Title: s.Title, Excerpt: truncateRunes(string(excerpt), aiExcerptChars)}
+	}
+	reasons, aiErr := neural.JustifyLinks(b.ai, string(content), candidates)
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("🔗 <b>Link suggestions</b> for <code>%s</code>\n", html.EscapeString(id)))
+	m := &tele.ReplyMarkup{}
+	var btns []tele.Btn
+	for _, s := range suggestions {
+		reason, ok := reasons[s.ID]
+		if aiErr == nil && !ok {
+			continue // The model judged it unrelated
+		}
+		actionID, err := b.propose(c.Chat().ID, "link", id, s.ID)
+		if err != nil {
+			return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+		}
+		num := len(btns) + 1
+		sb.WriteString(fmt.Sprintf("\n%d. [[%s]]", num, html.EscapeString(s.ID)))
+		if reason != "" {
+			sb.WriteString(" — " + html.EscapeString(reason))
+		}
+		sb.WriteString(fmt.Sprintf("\n<i>%s</i>\n", html.EscapeString(s.Signals())))
+		btns = append(btns, m.Data(fmt.Sprintf("🔗 %d", num), aiApplyBtn.Unique, strconv.FormatInt(actionID, 10)))
+	}
+
+	if len(btns) == 0 {
+		return c.Send(fmt.Sprintf("🔗 The model found none of the %d candidate(s) related to %s.", len(suggestions), id))
+	}
+	if aiErr != nil {
+		sb.WriteString(fmt.Sprintf("\n⚠ No justifications (AI Error: %s)\n", html.EscapeString(aiErr.Error())))
+	}
+	sb.WriteString("\nTap 🔗 to add the link.")
+	m.Inline(m.Split(5, btns)...)
+	return c.Send(sb.String(), &tele.SendOptions{ParseMode: tele.ModeHTML, ReplyMarkup: m})
+}
+
+// handleAIApply writes a proposal through the same path as the manual
+// command: /note link, /cue add, or a ## Resumen replacement.
+func (b *Bot) handleAIApply(c tele.Context) error {
+	id, err := strconv.ParseInt(c.Data(), 10, 64)
+	if err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: "Invalid proposal"})
+	}
+	action, err := b.claimPending(c.Chat().ID, id)
+	switch {
+	case errors.Is(err, errPendingGone):
+		return c.Respond(&tele.CallbackResponse{Text: "Proposal expired"})
+	case errors.Is(err, errPendingApplied):
+		return c.Respond(&tele.CallbackResponse{Text: "Already applied"})
+	case err != nil:
+		c.Respond()
+		return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+	}
+	c.Respond()
+
+	var msg string
+	switch action.Kind {
+	case "link":
+		msg, err = b.linkNotes(c.Chat().ID, action.NoteID, action.Payload)
+	case "cue":
+		msg, err = "✅ Cue Added", b.addCue(c.Chat().ID, action.NoteID, action.Payload)
+	case "resumen":
+		msg, err = "✅ Resumen Updated", b.setResumen(c.Chat().ID, action.NoteID, action.Payload)
+	default:
+		return c.Send(fmt.Sprintf("⛔ Error: unknown proposal kind %q", action.Kind))
+	}
+	if err != nil {
+		// Nothing was written: let a later tap (e.g. after a conflict) try again
+		b.releasePending(action.ID)
+		return sendNoteErr(c, err)
+	}
+	return c.Send(msg)
+}
+
+func (b *Bot) setResumen(chatID int64, id, text string) error {
+	path, err := b.resolvePath(id)
+	if err != nil {
+		return &resolveErr{id, err}
+	}
+	return b.mutate(chatID, "resumen", path, func(doc *markdown.Document) error {
+		return doc.SetSection("Resumen", text)
+	})
 }
@@ -0,0 +1,196 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+	"github.com/eliseohh/zettelcornelbot/internal/scheduler"
+	tele "gopkg.in/telebot.v3"
+)
+
+// The daily log is an ephemeral buffer: it lives in SQLite, never in the vault,
+// until an entry is promoted into a real note.
+
+const jobDailyPurge = "daily_purge"
+
+// Callback endpoint for promote buttons. Data: "<entryID>".
+var dailyBtn = &tele.Btn{Unique: "daily"}
+
+type dailyEntry struct {
+	ID       int64
+	At       time.Time
+	Text     string
+	Promoted string // Note path, "" if still ephemeral
+}
+
+func (b *Bot) registerDaily() {
+	b.api.Handle("/daily", b.handleDaily)
+	b.api.Handle(dailyBtn, b.handleDailyPromote)
+}
+
+// /daily <text> | show
+func (b *Bot) handleDaily(c tele.Context) error {
+	text := strings.TrimSpace(c.Message().Payload)
+	chatID := c.Chat().ID
+
+	if text == "" || strings.EqualFold(text, "show") {
+		return b.dailyShow(c, chatID)
+	}
+
+	now := b.sched.Now(chatID)
+	_, err := b.db.Exec("INSERT INTO daily_entries (chat_id, day, created_at, text) VALUES (?, ?, ?, ?)",
+		chatID, now.Format("2006-01-02"), now.Unix(), text)
+	if err != nil {
+		return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+	}
+	if err := b.ensureDailyPurge(chatID); err != nil {
+		return c.Send(fmt.Sprintf("⛔ Scheduler Error: %v", err))
+	}
+
+	return c.Send(fmt.Sprintf("📝 Logged %s (purged at %02d:00 unless promoted)", now.Format("15:04"), b.cfg.DailyPurgeHour))
+}
+
+func (b *Bot) dailyShow(c tele.Context, chatID int64) error {
+	day := b.sched.Now(chatID).Format("2006-01-02")
+	entries, err := b.dailyEntries(chatID, day)
+	if err != nil {
+		return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+	}
+	if len(entries) == 0 {
+		return c.Send("📭 Daily log empty. Usage: /daily <text>")
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("📓 Daily %s\n", day))
+	m := &tele.ReplyMarkup{}
+	var btns []tele.Btn
+	for i, e := range entries {
+		mark := ""
+		if e.Promoted != "" {
+			mark = " → " + e.Promoted
+		} else {
+			btns = append(btns, m.Data(fmt.Sprintf("💡 %d", i+1), dailyBtn.Unique, strconv.FormatInt(e.ID, 10)))
+		}
+		sb.WriteString(fmt.Sprintf("\n%d. [%s] %s%s", i+1, e.At.Format("15:04"), e.Text, mark))
+	}
+
+	if len(btns) == 0 {
+		return c.Send(sb.String())
+	}
+	sb.WriteString("\n\nTap 💡 to promote an entry to an idea note.")
+	m.Inline(m.Split(4, btns)...)
+	return c.Send(sb.String(), m)
+}
+
+func (b *Bot) handleDailyPromote(c tele.Context) error {
+	id, err := strconv.ParseInt(c.Data(), 10, 64)
+	if err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: "Invalid entry"})
+	}
+
+	var text, promoted string
+	err = b.db.QueryRow("SELECT text, COALESCE(promoted_note, '') FROM daily_entries WHERE id = ? AND chat_id = ?",
+		id, c.Chat().ID).Scan(&text, &promoted)
+	if err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: "Entry purged"})
+	}
+	if promoted != "" {
+		return c.Respond(&tele.CallbackResponse{Text: "Already promoted: " + promoted})
+	}
+
+	// Long or multi-line entries keep the full text in ## Notas under a one-line title.
+	title, notas := strings.Join(strings.Fields(text), " "), ""
+	if utf8.RuneCountInString(title) > markdown.DefaultLimits.TitleChars {
+		title = truncateRunes(title, markdown.DefaultLimits.TitleChars-1) + "…"
+	}
+	if title != text {
+		notas = text
+	}
+
+	relPath, err := b.createNote(c.Chat().ID, title, "idea", notas)
+	if err == errNoteExists {
+		c.Respond()
+		return c.Send("⛔ Error: Note already exists.")
+	}
+	if err != nil {
+		c.Respond()
+		return sendWriteErr(c, err)
+	}
+
+	b.db.Exec("UPDATE daily_entries SET promoted_note = ? WHERE id = ?", relPath, id)
+	c.Respond()
+	return c.Send(fmt.Sprintf("✅ Created: `%s`", relPath))
+}
+
+// dailyPurgeSpec is the cron spec for the configured purge hour.
+func (b *Bot) dailyPurgeSpec() string {
+	return fmt.Sprintf("0 %d * * *", b.cfg.DailyPurgeHour)
+}
+
+// ensureDailyPurge schedules the chat's end-of-day purge once. A job left from
+// a different daily_purge_hour is replaced.
+func (b *Bot) ensureDailyPurge(chatID int64) error {
+	jobs, err := b.sched.Jobs(chatID)
+	if err != nil {
+		return err
+	}
+	spec := b.dailyPurgeSpec()
+	for _, j := range jobs {
+		if j.Kind != jobDailyPurge {
+			continue
+		}
+		if j.Spec == spec {
+			return nil
+		}
+		if err := b.sched.Cancel(j.ID); err != nil {
+			return err
+		}
+	}
+	_, err = b.sched.Add(chatID, jobDailyPurge, spec, "")
+	return err
+}
+
+// dailyPurge is the scheduler job: drop today's (and older) unpromoted entries.
+// A job firing at an hour that is no longer configured only moves itself.
+func (b *Bot) dailyPurge(j scheduler.Job) error {
+	if j.Spec != b.dailyPurgeSpec() {
+		return b.ensureDailyPurge(j.ChatID)
+	}
+	day := b.sched.Now(j.ChatID).Format("2006-01-02")
+	_, err := b.db.Exec("DELETE FROM daily_entries WHERE chat_id = ? AND day <= ? AND promoted_note IS NULL", j.ChatID, day)
+	return err
+}
+
+func (b *Bot) dailyEntries(chatID int64, day string) ([]dailyEntry, error) {
+	rows, err := b.db.Query(`SELECT id, created_at, text, COALESCE(promoted_note, '')
+		FROM daily_entries WHERE chat_id = ? AND day = ? ORDER BY created_at, id`, chatID, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	loc := b.sched.Timezone(chatID)
+	var entries []dailyEntry
+	for rows.Next() {
+		var e dailyEntry
+		var at int64
+		if err := rows.Scan(&e.ID, &at, &e.Text, &e.Promoted); err != nil {
+			return nil, err
+		}
+		e.At = time.Unix(at, 0).In(loc)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(r[:n]))
+}
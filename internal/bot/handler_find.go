@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"path/filepath"
+	"strings"
+
+	"github.com/eliseohh/zettelcornelbot/internal/index"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	findLimit       = 10
+	similarLimit    = 5
+	semanticFindCmd = "/find~"
+)
+
+func (b *Bot) registerFind() {
+	b.api.Handle("/find", b.handleFind)
+	b.api.Handle("/similar", b.handleSimilar)
+}
+
+// /find <query>  e.g. /find tipo:libro memoria "working memory" recall*
+func (b *Bot) handleFind(c tele.Context) error {
+	query := strings.TrimSpace(c.Message().Payload)
+	if query == "" {
+		return c.Send("Usage: /find [tipo:<tipo>] <terms | \"phrase\" | prefix*>")
+	}
+
+	hits, err := b.db.Search(query, findLimit)
+	switch {
+	case err == index.ErrEmptyQuery:
+		return c.Send("Usage: /find [tipo:<tipo>] <terms | \"phrase\" | prefix*>")
+	case err == index.ErrNoFTS:
+		return c.Send("⛔ Search unavailable: index built without FTS5.")
+	case err != nil:
+		return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+	}
+
+	if len(hits) == 0 {
+		return c.Send(fmt.Sprintf("🔍 No results: %s", query))
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("🔍 <b>%d result(s)</b>\n", len(hits)))
+	for i, h := range hits {
+		folder := h.Folder
+		if folder == "." {
+			folder = "root"
+		}
+		sb.WriteString(fmt.Sprintf("\n%d. <code>%s</code> · 📂 %s\n", i+1, html.EscapeString(h.ID), html.EscapeString(folder)))
+		if h.Title != "" {
+			sb.WriteString(fmt.Sprintf("<b>%s</b>\n", html.EscapeString(h.Title)))
+		}
+		sb.WriteString(renderSnippet(h.Snippet) + "\n")
+	}
+
+	return c.Send(sb.String(), &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
+
+// renderSnippet escapes note text and turns the index highlight markers into <b>.
+func renderSnippet(s string) string {
+	s = html.EscapeString(strings.ReplaceAll(s, "\n", " "))
+	s = strings.ReplaceAll(s, index.HighlightStart, "<b>")
+	return strings.ReplaceAll(s, index.HighlightEnd, "</b>")
+}
+
+// /similar <ID>: nearest notes by embedding
+func (b *Bot) handleSimilar(c tele.Context) error {
+	id := strings.TrimSpace(c.Message().Payload)
+	if id == "" {
+		return c.Send("Usage: /similar <ID>")
+	}
+	if b.cfg.Embeddings == nil {
+		return c.Send("⛔ Embeddings disabled (set ai.embeddings = true).")
+	}
+	path, err := b.resolvePath(id)
+	if err != nil {
+		return sendResolveErr(c, id, err)
+	}
+	rel, err := filepath.Rel(b.cfg.RootDir, path)
+	if err != nil {
+		return c.Send(fmt.Sprintf("⛔ Error: %v", err))
+	}
+
+	matches, err := b.cfg.Embeddings.SimilarTo(rel, similarLimit)
+	switch {
+	case errors.Is(err, index.ErrNotEmbedded):
+		return c.Send(fmt.Sprintf("⏳ %s is not embedded yet. Try again in a moment.", id))
+	case err != nil:
+		return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+	}
+	return c.Send(renderMatches(fmt.Sprintf("Similar to <code>%s</code>", html.EscapeString(id)), matches), &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
+
+// /find~ <free query>: semantic search, sent as text (see register)
+func (b *Bot) handleSemanticFind(c tele.Context, query string) error {
+	if query == "" {
+		return c.Send("Usage: /find~ <free query>")
+	}
+	if b.cfg.Embeddings == nil {
+		return c.Send("⛔ Embeddings disabled (set ai.embeddings = true).")
+	}
+	matches, err := b.cfg.Embeddings.Search(query, similarLimit)
+	if err != nil {
+		return c.Send(fmt.Sprintf("⛔ AI Error: %v", err))
+	}
+	return c.Send(renderMatches(fmt.Sprintf("Closest to “%s”", html.EscapeString(query)), matches), &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
+
+func renderMatches(header string, matches []index.Match) string {
+	if len(matches) == 0 {
+		return fmt.Sprintf("🔍 %s: no embedded notes yet.", header)
+	}
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("🧭 <b>%s</b>\n", header))
+	for i, m := range matches {
+		sb.WriteString(fmt.Sprintf("\n%d. <code>%s</code> · %.0f%%", i+1, html.EscapeString(m.ID), m.Score*100))
+		if m.Title != "" {
+			sb.WriteString(" · " + html.EscapeString(m.Title))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,162 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eliseohh/zettelcornelbot/internal/index"
+	"github.com/eliseohh/zettelcornelbot/internal/vault"
+	tele "gopkg.in/telebot.v3"
+)
+
+// Every note write made by the bot is logged in note_ops with both versions,
+// so it can be listed (/history) and reverted (/undo).
+
+const historyLimit = 10
+
+type noteOp struct {
+	ID         int64
+	ChatID     int64
+	NoteID     string
+	Path       string // Relative to RootDir
+	Op         string
+	BeforeHash string // "" when the op created the note
+	AfterHash  string
+	Before     string
+	After      string
+	Diff       string
+	At         time.Time
+	Undone     bool
+}
+
+func (b *Bot) registerHistory() {
+	b.api.Handle("/undo", b.handleUndo)
+	b.api.Handle("/history", b.handleHistory)
+}
+
+// recordOp logs a write. A failure here doesn't undo the write; it only costs undo-ability.
+func (b *Bot) recordOp(chatID int64, op, path string, before, after []byte) {
+	rel, err := filepath.Rel(b.cfg.RootDir, path)
+	if err != nil {
+		rel = path
+	}
+	beforeHash := ""
+	if before != nil {
+		beforeHash = index.HashContent(before)
+	}
+	_, err = b.db.Exec(`INSERT INTO note_ops (chat_id, note_id, path, op, before_hash, after_hash, before, after, diff, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		chatID, noteID(path), rel, op, beforeHash, index.HashContent(after), string(before), string(after),
+		vault.Diff(string(before), string(after)), b.sched.Now(chatID).Unix())
+	if err != nil {
+		log.Printf("⚠️ Op log failed for %s: %v", rel, err)
+	}
+}
+
+// /undo reverts the chat's last operation that is still in effect.
+func (b *Bot) handleUndo(c tele.Context) error {
+	chatID := c.Chat().ID
+	op, err := b.scanOp(b.db.QueryRow(opColumns+` FROM note_ops
+		WHERE chat_id = ? AND undone_at IS NULL ORDER BY id DESC LIMIT 1`, chatID))
+	if err != nil {
+		return c.Send("📭 Nothing to undo.")
+	}
+
+	path := filepath.Join(b.cfg.RootDir, op.Path)
+	if op.BeforeHash == "" {
+		err = b.vault.Remove(path, op.AfterHash)
+	} else {
+		err = b.vault.Replace(path, op.AfterHash, []byte(op.Before))
+	}
+	if err == vault.ErrConflict {
+		return c.Send(fmt.Sprintf("⛔ Cannot undo %s on `%s`: the note changed since (edited outside the bot or by a later op).", op.Op, op.NoteID))
+	}
+	if err != nil {
+		return c.Send(fmt.Sprintf("⛔ Error: %v", err))
+	}
+
+	b.db.Exec("UPDATE note_ops SET undone_at = ? WHERE id = ?", b.sched.Now(chatID).Unix(), op.ID)
+	b.version(chatID, "undo", path)
+	b.reindex(path)
+
+	if op.BeforeHash == "" {
+		// A promoted /daily entry becomes ephemeral again
+		b.db.Exec("UPDATE daily_entries SET promoted_note = NULL WHERE promoted_note = ?", op.Path)
+		return c.Send(fmt.Sprintf("↩️ Undone: create `%s` (file removed)", op.NoteID))
+	}
+	return c.Send(fmt.Sprintf("↩️ Undone: %s on `%s`\n%s", op.Op, op.NoteID, invertDiff(op.Diff)))
+}
+
+// /history <ID>
+func (b *Bot) handleHistory(c tele.Context) error {
+	id := strings.TrimSpace(c.Message().Payload)
+	if id == "" {
+		return c.Send("Usage: /history <ID>")
+	}
+
+	rows, err := b.db.Query(opColumns+` FROM note_ops WHERE note_id = ? ORDER BY id DESC LIMIT ?`, id, historyLimit)
+	if err != nil {
+		return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+	}
+	defer rows.Close()
+
+	loc := b.sched.Timezone(c.Chat().ID)
+	sb := strings.Builder{}
+	n := 0
+	for rows.Next() {
+		op, err := b.scanOp(rows)
+		if err != nil {
+			return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+		}
+		added, removed := vault.DiffStat(op.Diff)
+		mark := ""
+		if op.Undone {
+			mark = " (undone)"
+		}
+		sb.WriteString(fmt.Sprintf("\n• %s %s +%d −%d%s", op.At.In(loc).Format("2006-01-02 15:04"), op.Op, added, removed, mark))
+		n++
+	}
+	if n == 0 {
+		return c.Send(fmt.Sprintf("📭 No bot edits recorded for `%s`.", id))
+	}
+	return c.Send(fmt.Sprintf("📜 History: `%s` (last %d)%s", id, n, sb.String()))
+}
+
+const opColumns = `SELECT id, chat_id, note_id, path, op, before_hash, after_hash, before, after, diff, created_at, undone_at IS NOT NULL`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (b *Bot) scanOp(row rowScanner) (*noteOp, error) {
+	var op noteOp
+	var at int64
+	err := row.Scan(&op.ID, &op.ChatID, &op.NoteID, &op.Path, &op.Op, &op.BeforeHash, &op.AfterHash,
+		&op.Before, &op.After, &op.Diff, &at, &op.Undone)
+	if err != nil {
+		return nil, err
+	}
+	op.At = time.Unix(at, 0)
+	return &op, nil
+}
+
+// invertDiff shows what an undo changed: the op's diff with +/- swapped.
+func invertDiff(diff string) string {
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	for i, l := range lines {
+		switch {
+		case strings.HasPrefix(l, "+"):
+			lines[i] = "-" + l[1:]
+		case strings.HasPrefix(l, "-"):
+			lines[i] = "+" + l[1:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func noteID(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
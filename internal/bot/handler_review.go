@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eliseohh/zettelcornelbot/internal/review"
+	tele "gopkg.in/telebot.v3"
+)
+
+// Callback endpoint for grade buttons. Data: "<cardID>|<grade>".
+var reviewBtn = &tele.Btn{Unique: "review"}
+
+func (b *Bot) registerReview() {
+	b.api.Handle("/review", b.handleReview)
+	b.api.Handle(reviewBtn, b.handleReviewGrade)
+}
+
+// /review serves due cues one at a time.
+func (b *Bot) handleReview(c tele.Context) error {
+	return b.sendNextCard(c, review.NewStore(b.db), time.Now())
+}
+
+func (b *Bot) handleReviewGrade(c tele.Context) error {
+	parts := strings.Split(c.Data(), "|")
+	if len(parts) != 2 {
+		return c.Respond(&tele.CallbackResponse{Text: "Invalid review action"})
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: "Invalid review action"})
+	}
+	grade, err := review.ParseGrade(parts[1])
+	if err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: "Invalid grade"})
+	}
+
+	now := time.Now()
+	store := review.NewStore(b.db)
+	card, err := store.Grade(id, grade, now)
+	if err == review.ErrNotDue {
+		// Double tap or an old message: the next card was already sent
+		return c.Respond(&tele.CallbackResponse{Text: "Already graded"})
+	}
+	if err != nil {
+		// Card vanished (cue edited or note deleted since it was shown)
+		c.Respond(&tele.CallbackResponse{Text: "Cue no longer exists"})
+		return b.sendNextCard(c, store, now)
+	}
+
+	c.Respond(&tele.CallbackResponse{Text: fmt.Sprintf("%s → next %s", grade, formatDue(card.Due.Sub(now)))})
+	return b.sendNextCard(c, store, now)
+}
+
+func (b *Bot) sendNextCard(c tele.Context, store *review.Store, now time.Time) error {
+	card, err := store.Next(now)
+	if err == review.ErrNoCardsDue {
+		return c.Send("✅ No cues due. Come back later.")
+	}
+	if err != nil {
+		return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+	}
+	due, _ := store.DueCount(now)
+
+	text := fmt.Sprintf("🧠 <b>Review</b> (%d due)\n\n❓ %s\n\n📄 %s · <code>%s</code>",
+		due, html.EscapeString(card.Cue), html.EscapeString(card.Title), html.EscapeString(card.NoteID))
+	return c.Send(text, &tele.SendOptions{ParseMode: tele.ModeHTML, ReplyMarkup: reviewMarkup(card.ID)})
+}
+
+func reviewMarkup(cardID int64) *tele.ReplyMarkup {
+	m := &tele.ReplyMarkup{}
+	labels := map[review.Grade]string{
+		review.Again: "🔁 Again",
+		review.Hard:  "😓 Hard",
+		review.Good:  "👍 Good",
+		review.Easy:  "⚡ Easy",
+	}
+	var row []tele.Btn
+	for g := review.Again; g <= review.Easy; g++ {
+		row = append(row, m.Data(labels[g], reviewBtn.Unique, strconv.FormatInt(cardID, 10), strconv.Itoa(int(g))))
+	}
+	m.Inline(m.Row(row...))
+	return m
+}
+
+func formatDue(d time.Duration) string {
+	if d < 24*time.Hour {
+		return fmt.Sprintf("in %dm", int(d.Round(time.Minute).Minutes()))
+	}
+	return fmt.Sprintf("in %dd", int(d.Round(time.Hour).Hours()/24))
+}
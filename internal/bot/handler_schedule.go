@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/eliseohh/zettelcornelbot/internal/scheduler"
+	tele "gopkg.in/telebot.v3"
+)
+
+// Job kinds handled by the bot.
+const jobMessage = "message" // Payload: text pushed verbatim
+
+// sender is the subset of *tele.Bot used for unsolicited messages (swapped in tests).
+type sender interface {
+	Send(to tele.Recipient, what interface{}, opts ...interface{}) (*tele.Message, error)
+}
+
+func (b *Bot) registerSchedule() {
+	b.api.Handle("/tz", b.handleTZ)
+	b.registerJobs()
+}
+
+// registerJobs binds scheduler job kinds to bot actions. Separate from
+// registerSchedule so tests can wire a scheduler without a Telegram API.
+func (b *Bot) registerJobs() {
+	b.sched.Handle(jobMessage, func(j scheduler.Job) error {
+		return b.push(j.ChatID, j.Payload)
+	})
+	b.sched.Handle(jobWorkPing, b.workPing)
+	b.sched.Handle(jobDailyPurge, b.dailyPurge)
+}
+
+// push sends a message the user did not ask for (scheduler driven).
+func (b *Bot) push(chatID int64, what interface{}, opts ...interface{}) error {
+	if b.out == nil {
+		log.Printf("Push to %d dropped: no sender", chatID)
+		return nil
+	}
+	_, err := b.out.Send(tele.ChatID(chatID), what, opts...)
+	return err
+}
+
+// /tz [Area/City]
+func (b *Bot) handleTZ(c tele.Context) error {
+	chatID := c.Chat().ID
+	tz := strings.TrimSpace(c.Message().Payload)
+	if tz == "" {
+		return c.Send(fmt.Sprintf("🕒 Timezone: %s\nUsage: /tz <Area/City>", b.sched.Timezone(chatID)))
+	}
+	if err := b.sched.SetTimezone(chatID, tz); err != nil {
+		return c.Send(fmt.Sprintf("⛔ Error: %v", err))
+	}
+	return c.Send(fmt.Sprintf("✅ Timezone: %s (now %s)", tz, b.sched.Now(chatID).Format("15:04")))
+}
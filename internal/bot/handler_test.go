@@ -1,13 +1,24 @@
 package bot
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/eliseohh/zettelcornelbot/internal/config"
 	"github.com/eliseohh/zettelcornelbot/internal/index"
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+	"github.com/eliseohh/zettelcornelbot/internal/neural"
+	"github.com/eliseohh/zettelcornelbot/internal/review"
+	"github.com/eliseohh/zettelcornelbot/internal/scheduler"
+	"github.com/eliseohh/zettelcornelbot/internal/vault"
+	"github.com/eliseohh/zettelcornelbot/internal/versioning"
 	tele "gopkg.in/telebot.v3"
 )
 
@@ -15,14 +26,43 @@ import (
 type MockContext struct {
 	tele.Context
 	PayloadVal string
+	TextVal    string
+	DataVal    string
+	UniqueVal  string // Set to simulate a callback button
+	ChatID     int64
+	UserID     int64
 	SentMsg    interface{}
+	SentOpts   []interface{}
+	Responded  *tele.CallbackResponse
 }
 
 func (m *MockContext) Message() *tele.Message {
-	return &tele.Message{Payload: m.PayloadVal}
+	return &tele.Message{Payload: m.PayloadVal, Text: m.TextVal}
+}
+func (m *MockContext) Sender() *tele.User {
+	return &tele.User{ID: m.UserID}
+}
+func (m *MockContext) Callback() *tele.Callback {
+	if m.UniqueVal == "" {
+		return nil
+	}
+	return &tele.Callback{Unique: m.UniqueVal, Data: m.DataVal}
 }
 func (m *MockContext) Send(what interface{}, opts ...interface{}) error {
 	m.SentMsg = what
+	m.SentOpts = opts
+	return nil
+}
+func (m *MockContext) Chat() *tele.Chat {
+	return &tele.Chat{ID: m.ChatID}
+}
+func (m *MockContext) Data() string {
+	return m.DataVal
+}
+func (m *MockContext) Respond(resp ...*tele.CallbackResponse) error {
+	if len(resp) > 0 {
+		m.Responded = resp[0]
+	}
 	return nil
 }
 
@@ -37,10 +77,13 @@ func TestBotHandlers(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	if err := db.Migrate(); err != nil {
+		t.Fatal(err)
+	}
 
 	// Create Bot Instance
 	cfg := Config{RootDir: tmpDir}
-	b := &Bot{db: db, cfg: cfg}
+	b := &Bot{db: db, idx: index.NewIndexer(db), sched: scheduler.New(db, scheduler.SystemClock{}, time.UTC), vault: vault.New(tmpDir, db), cfg: cfg}
 
 	// Test 1: Note Create
 	t.Run("Note Create Success", func(t *testing.T) {
@@ -60,6 +103,11 @@ func TestBotHandlers(t *testing.T) {
 		if _, err := os.Stat(expectedPath); os.IsNotExist(err) {
 			t.Error("File not created")
 		}
+
+		// Bot writes are indexed immediately
+		if rel, err := db.LookupPath(date + "-test-note"); err != nil || rel != date+"-test-note.md" {
+			t.Errorf("Expected note indexed, got %q (%v)", rel, err)
+		}
 	})
 
 	// Test 1.1: Note Create with Folder (Libro)
@@ -82,6 +130,125 @@ func TestBotHandlers(t *testing.T) {
 		}
 	})
 
+	// Test 1.2: IDs resolve inside category folders
+	t.Run("Cue Add Subfolder", func(t *testing.T) {
+		date := time.Now().Format("20060102")
+		ctx := &MockContext{PayloadVal: "add " + date + "-my-book What is it about?"}
+		if err := b.handleCue(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		msg := ctx.SentMsg.(string)
+		if !strings.Contains(msg, "✅ Cue Added") {
+			t.Errorf("Expected success in subfolder, got: %s", msg)
+		}
+	})
+
+	// Test 1.3: Same basename in two folders is reported, not guessed
+	t.Run("Validate Ambiguous", func(t *testing.T) {
+		date := time.Now().Format("20060102")
+		os.MkdirAll(filepath.Join(tmpDir, "estudio"), 0755)
+		dup := filepath.Join(tmpDir, "estudio", date+"-my-book.md")
+		os.WriteFile(dup, []byte("# Dup\n"), 0644)
+		defer os.Remove(dup)
+
+		ctx := &MockContext{PayloadVal: "validate " + date + "-my-book"}
+		if err := b.handleNote(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		msg := ctx.SentMsg.(string)
+		if !strings.Contains(msg, "Ambiguous") || !strings.Contains(msg, "libro/") || !strings.Contains(msg, "estudio/") {
+			t.Errorf("Expected ambiguity report, got: %s", msg)
+		}
+	})
+
+	t.Run("Validate Reports All Violations", func(t *testing.T) {
+		bad := filepath.Join(tmpDir, "bad-note.md")
+		os.WriteFile(bad, []byte("# Bad\nFecha: 2024-02-02\n\n## Cues\n- No mark\n- Also none\n"), 0644)
+		defer os.Remove(bad)
+
+		ctx := &MockContext{PayloadVal: "validate bad-note"}
+		if err := b.handleNote(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		msg := ctx.SentMsg.(string)
+		for _, want := range []string{"❌ Invalid", "❌ L5:9 CueMissingQuestionMark", "❌ L6:11 CueMissingQuestionMark", "❌ L3:1 MissingTipo", "MissingSection: missing section '## Notas'"} {
+			if !strings.Contains(msg, want) {
+				t.Errorf("Expected %q in: %s", want, msg)
+			}
+		}
+	})
+
+	// Test 1.4: Full-text search
+	t.Run("Find", func(t *testing.T) {
+		ctx := &MockContext{PayloadVal: "tipo:libro book"}
+		if err := b.handleFind(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		msg := ctx.SentMsg.(string)
+		if !b.db.HasFTS() {
+			if !strings.Contains(msg, "unavailable") {
+				t.Errorf("Expected FTS5 unavailable msg, got: %s", msg)
+			}
+			return
+		}
+		date := time.Now().Format("20060102")
+		if !strings.Contains(msg, date+"-my-book") || !strings.Contains(msg, "libro") {
+			t.Errorf("Expected libro hit, got: %s", msg)
+		}
+	})
+
+	// Test 1.5: Review serves the cue added above and reschedules it
+	t.Run("Review", func(t *testing.T) {
+		ctx := &MockContext{}
+		if err := b.handleReview(ctx); err != nil {
+			t.Fatal(err)
+		}
+		msg := ctx.SentMsg.(string)
+		if !strings.Contains(msg, "What is it about?") {
+			t.Fatalf("Expected cue, got: %s", msg)
+		}
+
+		var cardID int64
+		db.QueryRow("SELECT id FROM review_cards").Scan(&cardID)
+		grade := &MockContext{DataVal: fmt.Sprintf("%d|%d", cardID, review.Good)}
+		if err := b.handleReviewGrade(grade); err != nil {
+			t.Fatal(err)
+		}
+		if grade.Responded == nil || !strings.Contains(grade.Responded.Text, "Good") {
+			t.Errorf("Expected grade toast, got: %+v", grade.Responded)
+		}
+		if msg := grade.SentMsg.(string); !strings.Contains(msg, "No cues due") {
+			t.Errorf("Expected empty queue, got: %s", msg)
+		}
+
+		// Double tap: rejected without rescheduling or sending another card
+		again := &MockContext{DataVal: grade.DataVal}
+		if err := b.handleReviewGrade(again); err != nil {
+			t.Fatal(err)
+		}
+		if again.Responded == nil || again.Responded.Text != "Already graded" || again.SentMsg != nil {
+			t.Errorf("Expected already graded, got: %+v", again.Responded)
+		}
+	})
+
+	// Test 1.6: Per-chat timezone
+	t.Run("Timezone", func(t *testing.T) {
+		ctx := &MockContext{PayloadVal: "America/Santiago", ChatID: 42}
+		if err := b.handleTZ(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if msg := ctx.SentMsg.(string); !strings.Contains(msg, "✅ Timezone: America/Santiago") {
+			t.Errorf("Expected timezone set, got: %s", msg)
+		}
+		if loc := b.sched.Timezone(42); loc.String() != "America/Santiago" {
+			t.Errorf("Timezone not stored: %s", loc)
+		}
+	})
+
 	// Test 2: Cue Add Strictness
 	t.Run("Cue Add Invalid", func(t *testing.T) {
 		// Needs an existing note ID first. from prev test: date-test-note
@@ -95,6 +262,28 @@ func TestBotHandlers(t *testing.T) {
 		if !strings.Contains(msg, "must end with '?'") {
 			t.Errorf("Strict cues check failed, got: %s", msg)
 		}
+
+		// Same length limit as AI-proposed cues
+		ctx = &MockContext{PayloadVal: "add " + id + " " + strings.Repeat("a", markdown.DefaultLimits.CueLen) + "?"}
+		b.handleCue(ctx)
+		if msg := ctx.SentMsg.(string); !strings.Contains(msg, fmt.Sprintf("(max %d)", markdown.DefaultLimits.CueLen)) {
+			t.Errorf("Cue length check failed, got: %s", msg)
+		}
+	})
+
+	t.Run("Cue Add Multi-line", func(t *testing.T) {
+		id := time.Now().Format("20060102") + "-test-note"
+		path, _ := b.resolvePath(id)
+		before, _ := os.ReadFile(path)
+
+		ctx := &MockContext{PayloadVal: "add " + id + " What?\n- Injected?\nplain line?"}
+		b.handleCue(ctx)
+		if msg := ctx.SentMsg.(string); !strings.Contains(msg, "single line") {
+			t.Errorf("Expected a multi-line cue rejected, got: %s", msg)
+		}
+		if after, _ := os.ReadFile(path); string(after) != string(before) {
+			t.Errorf("Multi-line cue written:\n%s", after)
+		}
 	})
 
 	t.Run("Cue Add Valid", func(t *testing.T) {
@@ -112,6 +301,54 @@ func TestBotHandlers(t *testing.T) {
 		}
 	})
 
+	t.Run("Cue Add Over Limit", func(t *testing.T) {
+		id := time.Now().Format("20060102") + "-test-note"
+		path := filepath.Join(tmpDir, id+".md")
+
+		// One cue already there; fill up to the limit
+		for i := 1; i < markdown.MaxCuesCount; i++ {
+			b.cueAdd(&MockContext{}, id, fmt.Sprintf("Filler %d?", i))
+		}
+		before, _ := os.ReadFile(path)
+
+		ctx := &MockContext{}
+		b.cueAdd(ctx, id, "One too many?")
+		msg := ctx.SentMsg.(string)
+		if !strings.Contains(msg, "⛔ Rejected") || !strings.Contains(msg, "TooManyCues") {
+			t.Errorf("Expected rejection, got: %s", msg)
+		}
+		if after, _ := os.ReadFile(path); string(after) != string(before) {
+			t.Error("Rejected edit must not touch the file")
+		}
+	})
+
+	t.Run("Cue Add External Edit", func(t *testing.T) {
+		id := time.Now().Format("20060102") + "-my-book"
+		path := filepath.Join(tmpDir, "libro", id+".md")
+
+		// Edited outside the bot, not reindexed yet
+		content, _ := os.ReadFile(path)
+		edited := strings.Replace(string(content), "## Resumen\n", "## Resumen\nEdited elsewhere.\n", 1)
+		os.WriteFile(path, []byte(edited), 0644)
+
+		ctx := &MockContext{}
+		b.cueAdd(ctx, id, "Is this safe?")
+		if msg := ctx.SentMsg.(string); !strings.Contains(msg, "⛔ Conflict") {
+			t.Fatalf("Expected conflict, got: %s", msg)
+		}
+		if got, _ := os.ReadFile(path); string(got) != edited {
+			t.Error("External edit overwritten")
+		}
+
+		// The conflict refreshed the index: a retry applies on top of the external edit
+		ctx = &MockContext{}
+		b.cueAdd(ctx, id, "Is this safe?")
+		got, _ := os.ReadFile(path)
+		if msg := ctx.SentMsg.(string); !strings.Contains(msg, "✅ Cue Added") || !strings.Contains(string(got), "Edited elsewhere.") {
+			t.Errorf("Expected retry to succeed, got: %s\n%s", msg, got)
+		}
+	})
+
 	// Test 3: Note Link
 	t.Run("Note Link", func(t *testing.T) {
 		date := time.Now().Format("20060102")
@@ -140,4 +377,689 @@ func TestBotHandlers(t *testing.T) {
 			t.Errorf("Expected linked msg, got: %s", msg)
 		}
 	})
+
+	// Test 3.1: Outgoing links and backlinks
+	t.Run("Note Links", func(t *testing.T) {
+		date := time.Now().Format("20060102")
+		b.noteLink(&MockContext{}, date+"-my-book", date+"-test-note")
+
+		ctx := &MockContext{PayloadVal: "links " + date + "-test-note"}
+		if err := b.handleNote(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		msg := ctx.SentMsg.(string)
+		if !strings.Contains(msg, "→ Outgoing (1)\n └── `some-other-id` ⚠ dangling") {
+			t.Errorf("Expected dangling outlink, got: %s", msg)
+		}
+		if !strings.Contains(msg, "← Backlinks (1)\n └── `"+date+"-my-book` My Book") {
+			t.Errorf("Expected backlink, got: %s", msg)
+		}
+	})
+
+	t.Run("Note Doctor", func(t *testing.T) {
+		ctx := &MockContext{PayloadVal: "doctor"}
+		if err := b.handleNote(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		msg := ctx.SentMsg.(string)
+		if !strings.HasPrefix(msg, "🩺 Doctor") || !strings.Contains(msg, "-> [[some-other-id]]") {
+			t.Errorf("Expected dangling link in report, got: %s", msg)
+		}
+	})
+}
+
+func TestResolvePathIndexed(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	db, err := index.NewDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Note outside any category folder, only reachable through the index or the walk
+	root := filepath.Join(tmpDir, "vault")
+	nested := filepath.Join(root, "archivo", "2024")
+	os.MkdirAll(nested, 0755)
+	os.WriteFile(filepath.Join(nested, "deep-note.md"), []byte("# Deep\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\n\n## Cues\n\n## Resumen\n\n## Enlaces\n"), 0644)
+
+	if err := index.NewIndexer(db).Sync(root); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Bot{db: db, cfg: Config{RootDir: root}}
+	path, err := b.resolvePath("deep-note")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(nested, "deep-note.md") {
+		t.Errorf("Unexpected path: %s", path)
+	}
+
+	if _, err := b.resolvePath("missing"); err != errNotFound {
+		t.Errorf("Expected errNotFound, got: %v", err)
+	}
+
+	// Same basename in another nested folder: reported, not resolved to the indexed one
+	other := filepath.Join(root, "archivo", "2023")
+	os.MkdirAll(other, 0755)
+	os.WriteFile(filepath.Join(other, "deep-note.md"), []byte("# Older\nFecha: 2023-02-02\nTipo: idea\n\n## Notas\n\n## Cues\n\n## Resumen\n\n## Enlaces\n"), 0644)
+	if err := index.NewIndexer(db).Sync(root); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join("archivo", "2023", "deep-note.md"), filepath.Join("archivo", "2024", "deep-note.md")}
+	var amb *AmbiguousError
+	if _, err := b.resolvePath("deep-note"); !errors.As(err, &amb) || !reflect.DeepEqual(amb.Paths, want) {
+		t.Errorf("Expected ambiguous %v, got: %v", want, err)
+	}
+}
+
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) Now() time.Time          { return f.t }
+func (f *fakeClock) Advance(d time.Duration) { f.t = f.t.Add(d) }
+
+// fakeSender records scheduler pushes.
+type fakeSender struct {
+	sent []interface{}
+}
+
+func (f *fakeSender) Send(to tele.Recipient, what interface{}, opts ...interface{}) (*tele.Message, error) {
+	f.sent = append(f.sent, what)
+	return &tele.Message{}, nil
+}
+
+// newTestBot wires a Bot over a temp vault and indexed DB, with a fake clock and sender.
+func newTestBot(t *testing.T, clock scheduler.Clock) (*Bot, *fakeSender) {
+	t.Helper()
+	tmpDir := t.TempDir()
+
+	db, err := index.NewDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	root := filepath.Join(tmpDir, "vault")
+	os.Mkdir(root, 0755)
+
+	out := &fakeSender{}
+	b := &Bot{
+		out:   out,
+		db:    db,
+		idx:   index.NewIndexer(db),
+		sched: scheduler.New(db, clock, time.UTC),
+		vault: vault.New(root, db),
+		ai:    &neural.Fake{},
+		cfg:   Config{RootDir: root},
+	}
+	b.registerJobs()
+	return b, out
+}
+
+func TestWorkMode(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC)}
+	b, out := newTestBot(t, clock)
+	chat := int64(7)
+
+	b.handleNote(&MockContext{PayloadVal: "create tarea Ship It"})
+	b.handleNote(&MockContext{PayloadVal: "create Not A Task"})
+	date := time.Now().Format("20060102")
+	task := date + "-ship-it"
+
+	// Only tarea notes
+	ctx := &MockContext{PayloadVal: "on " + date + "-not-a-task", ChatID: chat}
+	b.handleWork(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "needs a 'tarea'") {
+		t.Fatalf("Expected tipo rejection, got: %s", msg)
+	}
+
+	// The ping job cannot be stored: no session is left open behind it
+	b.db.Exec("CREATE TRIGGER no_jobs BEFORE INSERT ON jobs BEGIN SELECT RAISE(ABORT, 'jobs unavailable'); END")
+	ctx = &MockContext{PayloadVal: "on " + task, ChatID: chat}
+	b.handleWork(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "Scheduler Error") {
+		t.Fatalf("Expected scheduler error, got: %s", msg)
+	}
+	b.db.Exec("DROP TRIGGER no_jobs")
+
+	ctx = &MockContext{PayloadVal: "on " + task, ChatID: chat}
+	b.handleWork(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "Work ON: Ship It") {
+		t.Fatalf("Expected session start, got: %s", msg)
+	}
+
+	// Ping after 30 minutes, with check-in buttons
+	clock.Advance(30 * time.Minute)
+	b.sched.Tick()
+	if len(out.sent) != 1 || !strings.Contains(out.sent[0].(string), "¿Sigues en task") {
+		t.Fatalf("Expected check-in ping, got: %v", out.sent)
+	}
+
+	var sessionID int64
+	b.db.QueryRow("SELECT id FROM work_sessions").Scan(&sessionID)
+	other := &MockContext{DataVal: fmt.Sprintf("%d|done", sessionID), ChatID: 8}
+	b.handleWorkCheckIn(other)
+	if other.Responded == nil || !strings.Contains(other.Responded.Text, "already closed") || other.SentMsg != nil {
+		t.Errorf("Another chat must not close the session: %+v", other.Responded)
+	}
+	still := &MockContext{DataVal: fmt.Sprintf("%d|still", sessionID), ChatID: chat}
+	b.handleWorkCheckIn(still)
+	if still.Responded == nil || !strings.Contains(still.Responded.Text, "Keep going") {
+		t.Errorf("Expected ack, got: %+v", still.Responded)
+	}
+
+	// Off after 1h25m: total time reported, log written in ## Notas, pings stop
+	clock.Advance(55 * time.Minute)
+	ctx = &MockContext{PayloadVal: "off", ChatID: chat}
+	b.handleWork(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "1h25m focused") {
+		t.Fatalf("Expected total time, got: %s", msg)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(b.cfg.RootDir, "tarea", task+".md"))
+	if !strings.Contains(string(content), "## Notas\n- ⏱ 2024-02-02 09:00–10:25 (1h25m) off\n") {
+		t.Errorf("Expected log in Notas, got:\n%s", content)
+	}
+
+	clock.Advance(time.Hour)
+	b.sched.Tick()
+	if len(out.sent) != 1 {
+		t.Errorf("Pings continued after /work off: %v", out.sent)
+	}
+}
+
+func TestDailyLog(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC)}
+	b, _ := newTestBot(t, clock)
+	b.cfg.DailyPurgeHour = 23
+	chat := int64(7)
+
+	for _, text := range []string{"first thought", "Promote me"} {
+		ctx := &MockContext{PayloadVal: text, ChatID: chat}
+		b.handleDaily(ctx)
+		if msg := ctx.SentMsg.(string); !strings.Contains(msg, "📝 Logged 09:0") {
+			t.Fatalf("Expected entry logged, got: %s", msg)
+		}
+		clock.Advance(time.Minute)
+	}
+
+	ctx := &MockContext{PayloadVal: "show", ChatID: chat}
+	b.handleDaily(ctx)
+	msg := ctx.SentMsg.(string)
+	if !strings.Contains(msg, "1. [09:00] first thought") || !strings.Contains(msg, "2. [09:01] Promote me") {
+		t.Fatalf("Unexpected listing: %s", msg)
+	}
+	markup, ok := ctx.SentOpts[0].(*tele.ReplyMarkup)
+	if !ok || len(markup.InlineKeyboard[0]) != 2 {
+		t.Fatalf("Expected one promote button per entry, got: %+v", ctx.SentOpts)
+	}
+
+	// Promote the second entry into an idea note
+	var entryID int64
+	b.db.QueryRow("SELECT id FROM daily_entries WHERE text = 'Promote me'").Scan(&entryID)
+	promote := &MockContext{DataVal: fmt.Sprint(entryID), ChatID: chat}
+	b.handleDailyPromote(promote)
+	date := time.Now().Format("20060102")
+	if msg := promote.SentMsg.(string); !strings.Contains(msg, date+"-promote-me.md") {
+		t.Fatalf("Expected idea note, got: %s", msg)
+	}
+	if _, err := os.Stat(filepath.Join(b.cfg.RootDir, date+"-promote-me.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	// End of day: only the promoted entry survives
+	clock.t = time.Date(2024, 2, 2, 23, 0, 0, 0, time.UTC)
+	b.sched.Tick()
+
+	var left int
+	b.db.QueryRow("SELECT COUNT(*) FROM daily_entries").Scan(&left)
+	if left != 1 {
+		t.Errorf("Expected 1 entry after purge, got %d", left)
+	}
+
+	// daily_purge_hour changed: the stored job moves instead of purging at the old hour
+	b.handleDaily(&MockContext{PayloadVal: "late thought", ChatID: chat})
+	b.cfg.DailyPurgeHour = 22
+	clock.t = time.Date(2024, 2, 3, 23, 0, 0, 0, time.UTC)
+	b.sched.Tick()
+	b.db.QueryRow("SELECT COUNT(*) FROM daily_entries").Scan(&left)
+	if left != 2 {
+		t.Errorf("Purge ran at the old hour: %d entries left", left)
+	}
+	jobs, _ := b.sched.Jobs(chat)
+	if len(jobs) != 1 || jobs[0].Spec != "0 22 * * *" {
+		t.Errorf("Expected the purge rescheduled at 22:00, got %+v", jobs)
+	}
+	ctx = &MockContext{PayloadVal: "after the change", ChatID: chat}
+	b.handleDaily(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "purged at 22:00") {
+		t.Errorf("Unexpected reply: %s", msg)
+	}
+	if jobs, _ = b.sched.Jobs(chat); len(jobs) != 1 {
+		t.Errorf("Expected a single purge job, got %+v", jobs)
+	}
+}
+
+func TestUndoHistory(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC)}
+	b, _ := newTestBot(t, clock)
+	chat, other := int64(7), int64(8)
+
+	b.handleNote(&MockContext{PayloadVal: "create Undo Me", ChatID: chat})
+	id := time.Now().Format("20060102") + "-undo-me"
+	path := filepath.Join(b.cfg.RootDir, id+".md")
+	b.cueAdd(&MockContext{ChatID: chat}, id, "Kept?")
+	afterCue, _ := os.ReadFile(path)
+	b.noteLink(&MockContext{ChatID: chat}, id, "wrong-id")
+
+	ctx := &MockContext{PayloadVal: id, ChatID: chat}
+	b.handleHistory(ctx)
+	msg := ctx.SentMsg.(string)
+	if !strings.Contains(msg, "(last 3)") || !strings.Contains(msg, "2024-02-02 09:00 link +1 −0") {
+		t.Fatalf("Unexpected history: %s", msg)
+	}
+
+	// Another chat has nothing to undo
+	ctx = &MockContext{ChatID: other}
+	b.handleUndo(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "Nothing to undo") {
+		t.Errorf("Expected per-chat undo, got: %s", msg)
+	}
+
+	ctx = &MockContext{ChatID: chat}
+	b.handleUndo(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "↩️ Undone: link") || !strings.Contains(msg, "-- [[wrong-id]]") {
+		t.Errorf("Unexpected undo reply: %s", msg)
+	}
+	if got, _ := os.ReadFile(path); string(got) != string(afterCue) {
+		t.Errorf("Link not reverted:\n%s", got)
+	}
+	if out, _ := b.db.Outlinks(id); len(out) != 0 {
+		t.Errorf("Index not refreshed after undo: %+v", out)
+	}
+
+	// External edit: the cue op can no longer be reverted safely
+	os.WriteFile(path, append(afterCue, "extra\n"...), 0644)
+	ctx = &MockContext{ChatID: chat}
+	b.handleUndo(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "⛔ Cannot undo cue") {
+		t.Errorf("Expected refusal, got: %s", msg)
+	}
+
+	// Restore and walk back to the creation
+	os.WriteFile(path, afterCue, 0644)
+	b.handleUndo(&MockContext{ChatID: chat})
+	ctx = &MockContext{ChatID: chat}
+	b.handleUndo(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "Undone: create") {
+		t.Errorf("Expected create undone, got: %s", msg)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Created note should be removed")
+	}
+
+	ctx = &MockContext{PayloadVal: id, ChatID: chat}
+	b.handleHistory(ctx)
+	if msg := ctx.SentMsg.(string); strings.Count(msg, "(undone)") != 3 {
+		t.Errorf("Expected all ops undone: %s", msg)
+	}
+}
+
+func TestNoteDiff(t *testing.T) {
+	b, _ := newTestBot(t, &fakeClock{t: time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC)})
+	chat := int64(7)
+
+	b.handleNote(&MockContext{PayloadVal: "create Versioned", ChatID: chat})
+	id := time.Now().Format("20060102") + "-versioned"
+
+	ctx := &MockContext{PayloadVal: "diff " + id, ChatID: chat}
+	b.handleNote(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "Versioning disabled") {
+		t.Fatalf("Expected disabled notice, got: %s", msg)
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo, err := versioning.Open(b.cfg.RootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.cfg.Git = repo
+
+	b.cueAdd(&MockContext{ChatID: chat}, id, "Tracked?")
+	ctx = &MockContext{PayloadVal: "diff " + id, ChatID: chat}
+	b.handleNote(ctx)
+	msg := ctx.SentMsg.(string)
+	for _, want := range []string{"cue: " + id, "Chat: 7", "+- Tracked?"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Diff missing %q:\n%s", want, msg)
+		}
+	}
+
+	b.handleUndo(&MockContext{ChatID: chat})
+	ctx = &MockContext{PayloadVal: "diff " + id, ChatID: chat}
+	b.handleNote(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "undo: "+id) || !strings.Contains(msg, "-- Tracked?") {
+		t.Errorf("Undo not committed:\n%s", msg)
+	}
+}
+
+func TestAIBackend(t *testing.T) {
+	b, _ := newTestBot(t, &fakeClock{t: time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC)})
+	b.handleNote(&MockContext{PayloadVal: "create Gardening"})
+	id := time.Now().Format("20060102") + "-gardening"
+
+	fake := &neural.Fake{Reply: func(prompt string) (string, error) {
+		return `{"resumen": "Plants need light."}`, nil
+	}}
+	b.ai = fake
+	ctx := &MockContext{PayloadVal: "summarize " + id}
+	b.handleAI(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "Plants need light.") {
+		t.Errorf("Expected backend reply, got: %s", msg)
+	}
+	if prompts := fake.Prompts(); len(prompts) != 1 || !strings.Contains(prompts[0], "# Gardening") {
+		t.Errorf("Prompt should carry the note: %v", prompts)
+	}
+
+	b.ai = nil
+	ctx = &MockContext{PayloadVal: "summarize " + id}
+	b.handleAI(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "AI disabled") {
+		t.Errorf("Expected disabled notice, got: %s", msg)
+	}
+}
+
+func TestSimilar(t *testing.T) {
+	b, _ := newTestBot(t, &fakeClock{t: time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC)})
+	date := time.Now().Format("20060102")
+	for _, title := range []string{"Cats Sleep", "Sleepy Cats Purr", "Tax Deadline"} {
+		b.handleNote(&MockContext{PayloadVal: "create " + title})
+	}
+
+	ctx := &MockContext{PayloadVal: date + "-cats-sleep"}
+	b.handleSimilar(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "Embeddings disabled") {
+		t.Fatalf("Expected disabled notice, got: %s", msg)
+	}
+
+	b.cfg.Embeddings = index.NewEmbedder(b.db, b.ai, "fake/test")
+	ctx = &MockContext{PayloadVal: date + "-cats-sleep"}
+	b.handleSimilar(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "not embedded yet") {
+		t.Fatalf("Expected pending notice, got: %s", msg)
+	}
+
+	if _, err := b.cfg.Embeddings.Run(); err != nil {
+		t.Fatal(err)
+	}
+	ctx = &MockContext{PayloadVal: date + "-cats-sleep"}
+	b.handleSimilar(ctx)
+	msg := ctx.SentMsg.(string)
+	if !strings.Contains(msg, "1. <code>"+date+"-sleepy-cats-purr</code>") || strings.Count(msg, "-cats-sleep</code>") != 1 {
+		t.Errorf("Unexpected /similar reply:\n%s", msg)
+	}
+
+	ctx = &MockContext{TextVal: "/find~ tax deadline"}
+	b.handleSemanticFind(ctx, "tax deadline")
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "1. <code>"+date+"-tax-deadline</code>") {
+		t.Errorf("Unexpected /find~ reply:\n%s", msg)
+	}
+}
+
+func TestAILinks(t *testing.T) {
+	b, _ := newTestBot(t, &fakeClock{t: time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC)})
+	chat := int64(7)
+	date := time.Now().Format("20060102")
+	src, peer, loose, linked := date+"-memory-palace", date+"-spaced-repetition", date+"-loci-history", date+"-recall-basics"
+	b.createNote(chat, "Memory Palace", "idea", "Visual memory technique for recall.")
+	b.createNote(chat, "Spaced Repetition", "idea", "Recall practice strengthens memory.")
+	b.createNote(chat, "Loci History", "idea", "Ancient visual technique.")
+	b.createNote(chat, "Recall Basics", "idea", "Memory recall basics.")
+	b.createNote(chat, "Tax Deadline", "idea", "Quarterly filing.")
+	b.noteLink(&MockContext{ChatID: chat}, src, linked)
+
+	b.ai = &neural.Fake{Reply: func(prompt string) (string, error) {
+		return fmt.Sprintf("1. [%s]: Both train recall.\n2. %s: skip\n", peer, loose), nil
+	}}
+	ctx := &MockContext{PayloadVal: "links " + src, ChatID: chat}
+	b.handleAI(ctx)
+	msg := ctx.SentMsg.(string)
+	if !strings.Contains(msg, "1. [["+peer+"]] — Both train recall.") || strings.Contains(msg, loose) ||
+		strings.Contains(msg, linked) || strings.Contains(msg, "tax-deadline") {
+		t.Fatalf("Unexpected suggestions:\n%s", msg)
+	}
+	markup := ctx.SentOpts[0].(*tele.SendOptions).ReplyMarkup
+	if len(markup.InlineKeyboard) != 1 || len(markup.InlineKeyboard[0]) != 1 {
+		t.Fatalf("Expected one apply button: %+v", markup.InlineKeyboard)
+	}
+	var actionID int64
+	b.db.QueryRow("SELECT id FROM pending_actions WHERE note_id = ? AND payload = ?", src, peer).Scan(&actionID)
+	data := fmt.Sprint(actionID)
+
+	apply := &MockContext{UniqueVal: aiApplyBtn.Unique, DataVal: data, ChatID: chat}
+	b.handleAIApply(apply)
+	if msg := apply.SentMsg.(string); msg != "🔗 Linked: "+src+" -> "+peer {
+		t.Errorf("Apply should go through /note link: %s", msg)
+	}
+	content, _ := os.ReadFile(filepath.Join(b.cfg.RootDir, src+".md"))
+	if !strings.Contains(string(content), "[["+peer+"]]") {
+		t.Errorf("Link not written:\n%s", content)
+	}
+
+	again := &MockContext{UniqueVal: aiApplyBtn.Unique, DataVal: data, ChatID: chat}
+	b.handleAIApply(again)
+	if again.Responded == nil || again.Responded.Text != "Already applied" {
+		t.Errorf("Second tap should be rejected: %+v", again.Responded)
+	}
+	other := &MockContext{UniqueVal: aiApplyBtn.Unique, DataVal: data, ChatID: 8}
+	b.handleAIApply(other)
+	if other.Responded == nil || other.Responded.Text != "Proposal expired" {
+		t.Errorf("Other chats must not apply it: %+v", other.Responded)
+	}
+
+	// Without the model the ranked candidates are still offered, with their signals
+	b.ai = &neural.Fake{Reply: func(string) (string, error) { return "", fmt.Errorf("connection refused") }}
+	ctx = &MockContext{PayloadVal: "links " + src + " 1", ChatID: chat}
+	b.handleAI(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "shared:") || !strings.Contains(msg, "connection refused") {
+		t.Errorf("Expected signal-only fallback:\n%s", msg)
+	}
+}
+
+func TestAIProposals(t *testing.T) {
+	b, _ := newTestBot(t, &fakeClock{t: time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC)})
+	chat := int64(7)
+	id := time.Now().Format("20060102") + "-spacing"
+	b.createNote(chat, "Spacing", "idea", "Reviews spread over time beat cramming.")
+	path := filepath.Join(b.cfg.RootDir, id+".md")
+
+	apply := func(kind, payload string) *MockContext {
+		t.Helper()
+		var actionID int64
+		b.db.QueryRow("SELECT id FROM pending_actions WHERE kind = ? AND payload = ?", kind, payload).Scan(&actionID)
+		if actionID == 0 {
+			t.Fatalf("No %s proposal for %q", kind, payload)
+		}
+		ctx := &MockContext{UniqueVal: aiApplyBtn.Unique, DataVal: fmt.Sprint(actionID), ChatID: chat}
+		b.handleAIApply(ctx)
+		return ctx
+	}
+
+	b.ai = &neural.Fake{Reply: func(string) (string, error) { return `{"resumen": "Spread reviews out."}`, nil }}
+	ctx := &MockContext{PayloadVal: "summarize " + id, ChatID: chat}
+	b.handleAI(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "Spread reviews out.") || !strings.Contains(msg, "(19/500 chars)") {
+		t.Errorf("Unexpected summary proposal:\n%s", msg)
+	}
+	if msg := apply("resumen", "Spread reviews out.").SentMsg.(string); msg != "✅ Resumen Updated" {
+		t.Fatalf("Apply failed: %s", msg)
+	}
+	content, _ := os.ReadFile(path)
+	if !strings.Contains(string(content), "## Resumen\nSpread reviews out.\n") {
+		t.Errorf("Resumen not written:\n%s", content)
+	}
+
+	b.ai = &neural.Fake{Reply: func(string) (string, error) {
+		return `{"cues": ["Why space reviews?", "Is cramming effective", "When should you review?"]}`, nil
+	}}
+	ctx = &MockContext{PayloadVal: "cues " + id, ChatID: chat}
+	b.handleAI(ctx)
+	msg := ctx.SentMsg.(string)
+	if !strings.Contains(msg, "1. Why space reviews?") || !strings.Contains(msg, "Is cramming effective — must end with &#39;?&#39;") {
+		t.Errorf("Unexpected cue proposals:\n%s", msg)
+	}
+	if msg := apply("cue", "When should you review?").SentMsg.(string); msg != "✅ Cue Added" {
+		t.Fatalf("Apply failed: %s", msg)
+	}
+	content, _ = os.ReadFile(path)
+	if !strings.Contains(string(content), "- When should you review?") || strings.Contains(string(content), "Why space") {
+		t.Errorf("Only the tapped cue should be added:\n%s", content)
+	}
+
+	// A multi-line cue reaching Apply (e.g. stored before validation) adds nothing
+	b.propose(chat, "cue", id, "Why?\n- Injected?\nplain line?")
+	if msg := apply("cue", "Why?\n- Injected?\nplain line?").SentMsg.(string); !strings.Contains(msg, "single line") {
+		t.Errorf("Expected a multi-line cue rejected, got: %s", msg)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(content) {
+		t.Errorf("Multi-line cue written:\n%s", after)
+	}
+
+	// An outside edit makes the first tap conflict; the proposal stays applicable
+	b.ai = &neural.Fake{Reply: func(string) (string, error) { return `{"resumen": "Space it out."}`, nil }}
+	b.handleAI(&MockContext{PayloadVal: "summarize " + id, ChatID: chat})
+	content, _ = os.ReadFile(path)
+	os.WriteFile(path, []byte(strings.Replace(string(content), "beat cramming.", "beat cramming!", 1)), 0644)
+	if msg := apply("resumen", "Space it out.").SentMsg.(string); !strings.Contains(msg, "Conflict") {
+		t.Fatalf("Expected conflict, got: %s", msg)
+	}
+	if msg := apply("resumen", "Space it out.").SentMsg.(string); msg != "✅ Resumen Updated" {
+		t.Fatalf("Retry after conflict failed: %s", msg)
+	}
+	if ctx := apply("resumen", "Space it out."); ctx.Responded == nil || ctx.Responded.Text != "Already applied" {
+		t.Errorf("Expected a third tap to be refused: %+v", ctx.Responded)
+	}
+	content, _ = os.ReadFile(path)
+	if !strings.Contains(string(content), "beat cramming!") || !strings.Contains(string(content), "## Resumen\nSpace it out.\n") {
+		t.Errorf("Retry should apply on top of the outside edit:\n%s", content)
+	}
+}
+
+func TestAIDraft(t *testing.T) {
+	b, _ := newTestBot(t, &fakeClock{t: time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC)})
+	chat := int64(7)
+	replies := []string{
+		`{"title": "Interleaving", "notas": "Mix topics.", "cues": ["Why mix topics"], "resumen": "Mixing helps.", "links": []}`,
+		`{"title": "Interleaving", "notas": "Mix topics in one session.", "cues": ["Why mix topics?"], "resumen": "Mixing helps.", "links": ["spacing", "invented"]}`,
+	}
+	b.db.Exec("INSERT INTO nodes (id, path, hash, last_mod) VALUES ('spacing', 'spacing.md', 'h', 0)")
+	b.ai = &neural.Fake{Reply: func(string) (string, error) {
+		reply := replies[0]
+		replies = replies[min(1, len(replies)-1):]
+		return reply, nil
+	}}
+
+	ctx := &MockContext{PayloadVal: "draft estudio interleaved practice", ChatID: chat}
+	b.handleAI(ctx)
+	rel := filepath.Join("estudio", time.Now().Format("20060102")+"-interleaving.md")
+	msg := ctx.SentMsg.(string)
+	if !strings.Contains(msg, "✅ Draft created: <code>"+rel+"</code>") || !strings.Contains(msg, "↻ retried") || !strings.Contains(msg, "missing notes: invented") {
+		t.Fatalf("Unexpected reply:\n%s", msg)
+	}
+	content, err := os.ReadFile(filepath.Join(b.cfg.RootDir, rel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Tipo: estudio", "- Why mix topics?", "- [[spacing]]"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Draft missing %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(string(content), "[[invented]]") {
+		t.Errorf("Draft links a note that does not exist:\n%s", content)
+	}
+
+	// Same title again: the existing note is left alone
+	ctx = &MockContext{PayloadVal: "draft estudio interleaved practice", ChatID: chat}
+	b.handleAI(ctx)
+	if msg := ctx.SentMsg.(string); !strings.Contains(msg, "already exists") {
+		t.Errorf("Expected exists error, got: %s", msg)
+	}
+
+	b.handleUndo(&MockContext{ChatID: chat})
+	if _, err := os.Stat(filepath.Join(b.cfg.RootDir, rel)); !os.IsNotExist(err) {
+		t.Errorf("/undo should remove the draft: %v", err)
+	}
+}
+
+func TestAccessControl(t *testing.T) {
+	b, _ := newTestBot(t, &fakeClock{t: time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC)})
+	acl, err := config.ParseACL("1:owner, 2:reader # comment\n3:reviewer\n-100:reader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.cfg.Access = acl
+
+	if _, err := config.ParseACL("4:admin"); err == nil {
+		t.Error("Expected unknown role error")
+	}
+
+	cases := []struct {
+		name    string
+		ctx     *MockContext
+		allowed bool
+	}{
+		{"Owner Create", &MockContext{UserID: 1, ChatID: 1, TextVal: "/note create X"}, true},
+		{"Reader Links", &MockContext{UserID: 2, ChatID: 2, TextVal: "/note@ZettelBot links x"}, true},
+		{"Reader Find", &MockContext{UserID: 2, ChatID: 2, TextVal: "/find cornell"}, true},
+		{"Reader Create", &MockContext{UserID: 2, ChatID: 2, TextVal: "/note create X"}, false},
+		{"Reviewer Review", &MockContext{UserID: 3, ChatID: 3, TextVal: "/review"}, true},
+		{"Reviewer Grade Button", &MockContext{UserID: 3, ChatID: 3, UniqueVal: reviewBtn.Unique, DataVal: "1|good"}, true},
+		{"Reviewer Work Button", &MockContext{UserID: 3, ChatID: 3, UniqueVal: workBtn.Unique, DataVal: "1|done"}, false},
+		{"Reviewer Status", &MockContext{UserID: 3, ChatID: 3, TextVal: "/status"}, false},
+		{"Group Member", &MockContext{UserID: 9, ChatID: -100, TextVal: "/status"}, true},
+		{"Owner In Reader Group", &MockContext{UserID: 1, ChatID: -100, TextVal: "/undo"}, true},
+		{"Stranger", &MockContext{UserID: 99, ChatID: 99, TextVal: "/note create Spam"}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			b.authorize(func(tele.Context) error { called = true; return nil })(tc.ctx)
+			if called != tc.allowed {
+				t.Errorf("allowed = %v, want %v", called, tc.allowed)
+			}
+		})
+	}
+
+	// Known users are told why; strangers get silence. Every denial is audited.
+	ctx := &MockContext{UserID: 2, ChatID: 2, TextVal: "/note create X"}
+	b.authorize(func(tele.Context) error { return nil })(ctx)
+	if msg, _ := ctx.SentMsg.(string); !strings.Contains(msg, "role reader cannot use /note create") {
+		t.Errorf("Unexpected denial reply: %v", ctx.SentMsg)
+	}
+	ctx = &MockContext{UserID: 99, ChatID: 99, TextVal: "/status"}
+	b.authorize(func(tele.Context) error { return nil })(ctx)
+	if ctx.SentMsg != nil {
+		t.Errorf("Stranger should get no reply, got: %v", ctx.SentMsg)
+	}
+
+	var n int
+	var action, role string
+	b.db.QueryRow("SELECT COUNT(*) FROM audit_log WHERE event = 'denied'").Scan(&n)
+	b.db.QueryRow("SELECT action, role FROM audit_log WHERE user_id = 99 ORDER BY id LIMIT 1").Scan(&action, &role)
+	if n != 6 || action != "/note create" || role != "" {
+		t.Errorf("Unexpected audit log: n=%d action=%q role=%q", n, action, role)
+	}
 }
@@ -0,0 +1,235 @@
+package bot
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+	"github.com/eliseohh/zettelcornelbot/internal/scheduler"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	jobWorkPing   = "work_ping" // Payload: session ID
+	workPingEvery = "@every 30m"
+)
+
+// Callback endpoint for check-in buttons. Data: "<sessionID>|still|switched|done".
+var workBtn = &tele.Btn{Unique: "work"}
+
+type workSession struct {
+	ID        int64
+	ChatID    int64
+	NoteID    string
+	StartedAt time.Time
+	JobID     int64
+	Pings     int
+}
+
+func (b *Bot) registerWork() {
+	b.api.Handle("/work", b.handleWork)
+	b.api.Handle(workBtn, b.handleWorkCheckIn)
+}
+
+// /work on <tarea-ID> | off | (status)
+func (b *Bot) handleWork(c tele.Context) error {
+	args := strings.Fields(c.Message().Payload)
+	chatID := c.Chat().ID
+
+	if len(args) == 0 {
+		s, err := b.activeSession(chatID)
+		if err != nil {
+			return c.Send("💤 Work mode OFF. Usage: /work on <tarea-ID> | off")
+		}
+		elapsed := b.sched.Now(chatID).Sub(s.StartedAt)
+		return c.Send(fmt.Sprintf("⏱ Working on `%s` for %s", s.NoteID, formatSpan(elapsed)))
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "on":
+		if len(args) < 2 {
+			return c.Send("Usage: /work on <tarea-ID>")
+		}
+		return b.workOn(c, chatID, args[1])
+	case "off":
+		s, err := b.activeSession(chatID)
+		if err != nil {
+			return c.Send("💤 Work mode already OFF.")
+		}
+		summary, err := b.workClose(s, "off")
+		if err != nil {
+			return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+		}
+		return c.Send(summary)
+	default:
+		return c.Send("Usage: /work on <tarea-ID> | off")
+	}
+}
+
+func (b *Bot) workOn(c tele.Context, chatID int64, id string) error {
+	if s, err := b.activeSession(chatID); err == nil {
+		return c.Send(fmt.Sprintf("⛔ Already working on `%s`. Use /work off first.", s.NoteID))
+	}
+
+	path, err := b.resolvePath(id)
+	if err != nil {
+		return sendResolveErr(c, id, err)
+	}
+	note, err := markdown.ParseFile(path)
+	if err != nil {
+		return c.Send(fmt.Sprintf("❌ Invalid: %v", err))
+	}
+	if note.Type != markdown.TaskTipo {
+		return c.Send(fmt.Sprintf("⛔ Error: /work needs a '%s' note (`%s` is '%s').", markdown.TaskTipo, id, note.Type))
+	}
+
+	now := b.sched.Now(chatID)
+	res, err := b.db.Exec("INSERT INTO work_sessions (chat_id, note_id, started_at) VALUES (?, ?, ?)", chatID, id, now.Unix())
+	if err != nil {
+		return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+	}
+	sessionID, err := res.LastInsertId()
+	if err != nil {
+		return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+	}
+
+	// A session without its ping job would block every later /work on: drop it on failure
+	jobID, err := b.sched.Add(chatID, jobWorkPing, workPingEvery, strconv.FormatInt(sessionID, 10))
+	if err != nil {
+		b.db.Exec("DELETE FROM work_sessions WHERE id = ?", sessionID)
+		return c.Send(fmt.Sprintf("⛔ Scheduler Error: %v", err))
+	}
+	if _, err := b.db.Exec("UPDATE work_sessions SET job_id = ? WHERE id = ?", jobID, sessionID); err != nil {
+		b.sched.Cancel(jobID)
+		b.db.Exec("DELETE FROM work_sessions WHERE id = ?", sessionID)
+		return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+	}
+
+	return c.Send(fmt.Sprintf("⏱ Work ON: %s\nCheck-in every 30m. /work off to stop.", note.Title))
+}
+
+// workPing is the scheduler job: "¿Sigues en task X?"
+func (b *Bot) workPing(j scheduler.Job) error {
+	sessionID, err := strconv.ParseInt(j.Payload, 10, 64)
+	if err != nil {
+		return b.sched.Cancel(j.ID)
+	}
+	s, err := b.session(sessionID)
+	if err != nil {
+		// Session closed or gone: stop pinging
+		return b.sched.Cancel(j.ID)
+	}
+
+	b.db.Exec("UPDATE work_sessions SET pings = pings + 1 WHERE id = ?", s.ID)
+
+	m := &tele.ReplyMarkup{}
+	data := strconv.FormatInt(s.ID, 10)
+	m.Inline(m.Row(
+		m.Data("✅ Sigo", workBtn.Unique, data, "still"),
+		m.Data("🔀 Cambié", workBtn.Unique, data, "switched"),
+		m.Data("🏁 Terminé", workBtn.Unique, data, "done"),
+	))
+	elapsed := b.sched.Now(s.ChatID).Sub(s.StartedAt)
+	return b.push(s.ChatID, fmt.Sprintf("⏰ ¿Sigues en task `%s`? (%s)", s.NoteID, formatSpan(elapsed)), m)
+}
+
+func (b *Bot) handleWorkCheckIn(c tele.Context) error {
+	parts := strings.Split(c.Data(), "|")
+	if len(parts) != 2 {
+		return c.Respond(&tele.CallbackResponse{Text: "Invalid work action"})
+	}
+	sessionID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: "Invalid work action"})
+	}
+	s, err := b.session(sessionID)
+	if err != nil || s.ChatID != c.Chat().ID {
+		// Another chat's session looks the same as a closed one
+		return c.Respond(&tele.CallbackResponse{Text: "Session already closed"})
+	}
+
+	switch parts[1] {
+	case "still":
+		return c.Respond(&tele.CallbackResponse{Text: "👍 Keep going"})
+	case "switched", "done":
+		c.Respond()
+		summary, err := b.workClose(s, parts[1])
+		if err != nil {
+			return c.Send(fmt.Sprintf("⛔ DB Error: %v", err))
+		}
+		if parts[1] == "switched" {
+			summary += "\nUse /work on <tarea-ID> for the new task."
+		}
+		return c.Send(summary)
+	default:
+		return c.Respond(&tele.CallbackResponse{Text: "Invalid work action"})
+	}
+}
+
+// workClose ends a session, stops its pings and logs it in the task's ## Notas.
+// Returns the user-facing summary; if the session cannot be closed nothing else happens.
+func (b *Bot) workClose(s *workSession, outcome string) (string, error) {
+	end := b.sched.Now(s.ChatID)
+	_, err := b.db.Exec("UPDATE work_sessions SET ended_at = ?, outcome = ? WHERE id = ?", end.Unix(), outcome, s.ID)
+	if err != nil {
+		return "", err
+	}
+	if s.JobID != 0 {
+		b.sched.Cancel(s.JobID)
+	}
+
+	total := end.Sub(s.StartedAt)
+	summary := fmt.Sprintf("⏹ Work OFF: `%s` · %s focused (%s)", s.NoteID, formatSpan(total), outcome)
+
+	entry := fmt.Sprintf("- ⏱ %s–%s (%s) %s",
+		s.StartedAt.Format("2006-01-02 15:04"), end.Format("15:04"), formatSpan(total), outcome)
+	if err := b.appendWorkLog(s.ChatID, s.NoteID, entry); err != nil {
+		summary += fmt.Sprintf("\n⚠ Log not written: %v", err)
+	}
+	return summary, nil
+}
+
+// appendWorkLog adds a line at the end of ## Notas, only if the note stays valid.
+func (b *Bot) appendWorkLog(chatID int64, id, entry string) error {
+	path, err := b.resolvePath(id)
+	if err != nil {
+		return err
+	}
+	// e.g. 'Notas' would exceed the Notas limit: nothing is written
+	return b.mutate(chatID, "worklog", path, func(doc *markdown.Document) error {
+		return doc.AppendNotas(entry)
+	})
+}
+
+func (b *Bot) activeSession(chatID int64) (*workSession, error) {
+	return b.scanSession(b.db.QueryRow(`SELECT id, chat_id, note_id, started_at, COALESCE(job_id, 0), pings
+		FROM work_sessions WHERE chat_id = ? AND ended_at IS NULL ORDER BY id DESC LIMIT 1`, chatID))
+}
+
+// session returns an open session by ID.
+func (b *Bot) session(id int64) (*workSession, error) {
+	return b.scanSession(b.db.QueryRow(`SELECT id, chat_id, note_id, started_at, COALESCE(job_id, 0), pings
+		FROM work_sessions WHERE id = ? AND ended_at IS NULL`, id))
+}
+
+func (b *Bot) scanSession(row *sql.Row) (*workSession, error) {
+	var s workSession
+	var started int64
+	if err := row.Scan(&s.ID, &s.ChatID, &s.NoteID, &started, &s.JobID, &s.Pings); err != nil {
+		return nil, err
+	}
+	s.StartedAt = time.Unix(started, 0).In(b.sched.Timezone(s.ChatID))
+	return &s, nil
+}
+
+// formatSpan renders a duration as "1h25m" / "25m".
+func formatSpan(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if h := int(d.Hours()); h > 0 {
+		return fmt.Sprintf("%dh%02dm", h, int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dm", int(d.Minutes()))
+}
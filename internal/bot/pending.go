@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Proposals that wait for a button tap (e.g. /ai links) are stored in
+// pending_actions; the button carries only the row ID.
+
+// pendingTTL is how long a proposal button stays valid.
+const pendingTTL = 24 * time.Hour
+
+var (
+	errPendingGone    = errors.New("proposal expired or unknown")
+	errPendingApplied = errors.New("proposal already applied")
+)
+
+type pendingAction struct {
+	ID      int64
+	Kind    string
+	NoteID  string
+	Payload string
+}
+
+// propose stores an action for chatID and returns its ID. Expired rows are purged on the way.
+func (b *Bot) propose(chatID int64, kind, noteID, payload string) (int64, error) {
+	now := time.Now()
+	b.db.Exec("DELETE FROM pending_actions WHERE created_at < ?", now.Add(-pendingTTL).Unix())
+	res, err := b.db.Exec("INSERT INTO pending_actions (chat_id, kind, note_id, payload, created_at) VALUES (?, ?, ?, ?, ?)",
+		chatID, kind, noteID, payload, now.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// claimPending marks the action applied and returns it. Each action can be
+// claimed once, and only from the chat it was proposed in; a claim whose
+// write fails is undone with releasePending.
+func (b *Bot) claimPending(chatID, id int64) (pendingAction, error) {
+	a := pendingAction{ID: id}
+	var created int64
+	var applied sql.NullInt64
+	err := b.db.QueryRow("SELECT kind, note_id, payload, created_at, applied_at FROM pending_actions WHERE id = ? AND chat_id = ?",
+		id, chatID).Scan(&a.Kind, &a.NoteID, &a.Payload, &created, &applied)
+	if err == sql.ErrNoRows || (err == nil && time.Since(time.Unix(created, 0)) > pendingTTL) {
+		return a, errPendingGone
+	}
+	if err != nil {
+		return a, err
+	}
+	if applied.Valid {
+		return a, errPendingApplied
+	}
+
+	res, err := b.db.Exec("UPDATE pending_actions SET applied_at = ? WHERE id = ? AND applied_at IS NULL", time.Now().Unix(), id)
+	if err != nil {
+		return a, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return a, errPendingApplied // Lost a race with a double tap
+	}
+	return a, nil
+}
+
+// releasePending makes a claimed action applicable again.
+func (b *Bot) releasePending(id int64) {
+	b.db.Exec("UPDATE pending_actions SET applied_at = NULL WHERE id = ?", id)
+}
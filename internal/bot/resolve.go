@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+	tele "gopkg.in/telebot.v3"
+)
+
+// Known categories (the configured Tipos). "idea" lives at the vault root, the rest in a folder of the same name.
+func categories() []string { return markdown.Tipos }
+
+var errNotFound = errors.New("not found")
+
+// AmbiguousError is returned when the same ID exists in more than one folder.
+type AmbiguousError struct {
+	ID    string
+	Paths []string // Relative to RootDir
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("ambiguous id %q: %s", e.ID, strings.Join(e.Paths, ", "))
+}
+
+// resolvePath maps a note ID (filename without extension) to its absolute path.
+// Order: index lookup (nodes.id -> path, plus the duplicates the indexer
+// recorded for it), then category folders, then a full vault walk whenever
+// the index had no answer. Every existing candidate is collected so duplicates
+// across folders are reported, not guessed.
+func (b *Bot) resolvePath(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return "", errNotFound
+	}
+
+	found := map[string]bool{}
+	addIfExists := func(rel string) {
+		if info, err := os.Stat(filepath.Join(b.cfg.RootDir, rel)); err == nil && !info.IsDir() {
+			found[filepath.Clean(rel)] = true
+		}
+	}
+
+	// 1. Index (may be empty or stale, so every hit is re-checked on disk)
+	indexed := false
+	if b.db != nil {
+		if rel, err := b.db.LookupPath(id); err == nil {
+			addIfExists(rel)
+			indexed = found[filepath.Clean(rel)]
+			dups, _ := b.db.Duplicates(rel)
+			for _, dup := range dups {
+				addIfExists(dup)
+			}
+		}
+	}
+
+	// 2. Category folders
+	for _, cat := range categories() {
+		addIfExists(categoryRelPath(cat, id+".md"))
+	}
+
+	// 3. Not indexed: walk the vault (notes moved into nested folders by hand,
+	// or a nested duplicate of a category hit)
+	if !indexed {
+		filepath.WalkDir(b.cfg.RootDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if strings.HasPrefix(d.Name(), ".") && path != b.cfg.RootDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.Name() == id+".md" {
+				if rel, err := filepath.Rel(b.cfg.RootDir, path); err == nil {
+					found[rel] = true
+				}
+			}
+			return nil
+		})
+	}
+
+	switch len(found) {
+	case 0:
+		return "", errNotFound
+	case 1:
+		for rel := range found {
+			return filepath.Join(b.cfg.RootDir, rel), nil
+		}
+	}
+
+	paths := make([]string, 0, len(found))
+	for rel := range found {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	return "", &AmbiguousError{ID: id, Paths: paths}
+}
+
+// sendResolveErr renders a resolvePath failure for the user.
+func sendResolveErr(c tele.Context, id string, err error) error {
+	var amb *AmbiguousError
+	if errors.As(err, &amb) {
+		return c.Send(fmt.Sprintf("⛔ Ambiguous ID `%s`:\n- %s\nRename one of the files.", id, strings.Join(amb.Paths, "\n- ")))
+	}
+	return c.Send(fmt.Sprintf("🔍 Not Found: %s", id))
+}
+
+// categoryRelPath returns the vault-relative path of a file in a category folder.
+func categoryRelPath(category, filename string) string {
+	if category == "idea" {
+		return filename
+	}
+	return filepath.Join(category, filename)
+}
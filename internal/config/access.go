@@ -0,0 +1,95 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Role names what an allowlisted user may do (enforced by the bot).
+type Role string
+
+const (
+	RoleOwner    Role = "owner"    // Every command
+	RoleReader   Role = "reader"   // Read-only: status, search, links
+	RoleReviewer Role = "reviewer" // Active recall only
+)
+
+var Roles = []Role{RoleOwner, RoleReader, RoleReviewer}
+
+// ACL maps Telegram user or chat IDs to a role. A user entry wins over a chat
+// entry, so a group can be a reader while its admin stays owner.
+type ACL map[int64]Role
+
+// ParseACL reads "id:role" pairs separated by commas or newlines, e.g.
+// "12345:owner,-100777:reader". Blank entries and "#" comments are skipped.
+func ParseACL(spec string) (ACL, error) {
+	acl := ACL{}
+	for _, line := range strings.Split(spec, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		for _, entry := range strings.Split(line, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			idStr, roleStr, ok := strings.Cut(entry, ":")
+			if !ok {
+				return nil, fmt.Errorf("allowlist entry %q: want id:role", entry)
+			}
+			id, err := strconv.ParseInt(strings.TrimSpace(idStr), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("allowlist entry %q: invalid id", entry)
+			}
+			role := Role(strings.ToLower(strings.TrimSpace(roleStr)))
+			if !isRole(role) {
+				return nil, fmt.Errorf("allowlist entry %q: unknown role %q (owner|reader|reviewer)", entry, role)
+			}
+			acl[id] = role
+		}
+	}
+	return acl, nil
+}
+
+// LoadACL reads an allowlist file in ParseACL's format, one entry per line.
+func LoadACL(path string) (ACL, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	acl, err := ParseACL(strings.Join(lines, "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return acl, nil
+}
+
+// Role returns the role for an update's sender and chat ("" = not allowed).
+func (a ACL) Role(userID, chatID int64) Role {
+	if r, ok := a[userID]; ok {
+		return r
+	}
+	return a[chatID]
+}
+
+func isRole(r Role) bool {
+	for _, known := range Roles {
+		if r == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,370 @@
+// Package config loads the bot's settings: a TOML file (optional) with
+// environment overrides, validated once at startup.
+//
+// Precedence: defaults < file < environment.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/eliseohh/zettelcornelbot/internal/index"
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+	"github.com/eliseohh/zettelcornelbot/internal/neural"
+)
+
+// DefaultFile is loaded when no path is given and it exists in the working directory.
+const DefaultFile = "zettel.toml"
+
+type Config struct {
+	Vault     Vault     `toml:"vault"`
+	Index     Index     `toml:"index"`
+	Parser    Parser    `toml:"parser"`
+	Telegram  Telegram  `toml:"telegram"`
+	AI        AI        `toml:"ai"`
+	Scheduler Scheduler `toml:"scheduler"`
+
+	Path string `toml:"-"` // File it was loaded from ("" = defaults + env only)
+}
+
+type Vault struct {
+	Root       string   `toml:"root"`       // env ZETTEL_ROOT
+	Categories []string `toml:"categories"` // Allowed Tipos; "idea" is required (vault root)
+	Git        bool     `toml:"git"`        // env ZETTEL_GIT=1
+}
+
+type Index struct {
+	DB           string        `toml:"db"`            // env ZETTEL_DB
+	Workers      int           `toml:"workers"`       // env ZETTEL_WORKERS
+	SyncInterval time.Duration `toml:"sync_interval"` // env ZETTEL_SYNC_INTERVAL, e.g. "1h"
+}
+
+type Parser struct {
+	Mode   string `toml:"mode"` // strict|lenient, env ZETTEL_PARSER_MODE
+	Limits Limits `toml:"limits"`
+}
+
+// Limits may only tighten markdown.SpecLimits.
+type Limits struct {
+	TotalChars   int `toml:"total_chars"`
+	TitleChars   int `toml:"title_chars"`
+	NotasChars   int `toml:"notas_chars"`
+	ResumenChars int `toml:"resumen_chars"`
+	CuesCount    int `toml:"cues_count"`
+	CueLen       int `toml:"cue_len"`
+}
+
+type Telegram struct {
+	Token     string   `toml:"token"`      // env TELEGRAM_TOKEN; empty = indexer-only mode
+	Allow     []string `toml:"allow"`      // "id:role" entries, env ZETTEL_ALLOW (comma-separated)
+	AllowFile string   `toml:"allow_file"` // env ZETTEL_ALLOW_FILE
+}
+
+type AI struct {
+	Backend    string        `toml:"backend"`     // ollama|openai|fake, env ZETTEL_AI_BACKEND
+	URL        string        `toml:"url"`         // "" = backend default, env ZETTEL_AI_URL (or OLLAMA_URL)
+	Model      string        `toml:"model"`       // env ZETTEL_AI_MODEL (or OLLAMA_MODEL)
+	EmbedModel string        `toml:"embed_model"` // "" = model, env ZETTEL_AI_EMBED_MODEL
+	Embeddings bool          `toml:"embeddings"`  // Embed notes for /similar, env ZETTEL_AI_EMBEDDINGS
+	APIKey     string        `toml:"api_key"`     // openai only, env ZETTEL_AI_KEY
+	Timeout    time.Duration `toml:"timeout"`     // Per request, env ZETTEL_AI_TIMEOUT
+
+	MaxAttempts int `toml:"max_attempts"` // Tries per structured answer, env ZETTEL_AI_MAX_ATTEMPTS
+}
+
+type Scheduler struct {
+	Timezone       string `toml:"timezone"`         // IANA name or "Local", env ZETTEL_TZ
+	DailyPurgeHour int    `toml:"daily_purge_hour"` // env ZETTEL_DAILY_PURGE_HOUR
+}
+
+// Default is the configuration used when nothing is set.
+func Default() *Config {
+	spec := markdown.SpecLimits
+	return &Config{
+		Vault: Vault{Root: ".", Categories: append([]string(nil), markdown.Tipos...)},
+		Index: Index{
+			DB:           "./zettel.db",
+			Workers:      index.DefaultWorkers,
+			SyncInterval: time.Hour,
+		},
+		Parser: Parser{Mode: "strict", Limits: Limits{
+			TotalChars:   spec.TotalChars,
+			TitleChars:   spec.TitleChars,
+			NotasChars:   spec.NotasChars,
+			ResumenChars: spec.ResumenChars,
+			CuesCount:    spec.CuesCount,
+			CueLen:       spec.CueLen,
+		}},
+		AI:        AI{Backend: "ollama", Model: "llama3", Embeddings: true, Timeout: neural.DefaultTimeout, MaxAttempts: 3},
+		Scheduler: Scheduler{Timezone: "Local", DailyPurgeHour: 23},
+	}
+}
+
+// Load reads path (or DefaultFile if path is "" and the file exists), applies
+// env overrides and validates. Every problem found is reported in one error.
+func Load(path string) (*Config, error) {
+	c := Default()
+	if path == "" {
+		if _, err := os.Stat(DefaultFile); err == nil {
+			path = DefaultFile
+		}
+	}
+	if path != "" {
+		md, err := toml.DecodeFile(path, c)
+		if err != nil {
+			return nil, fmt.Errorf("config %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("config %s: unknown keys: %v", path, undecoded)
+		}
+		c.Path = path
+	}
+	if err := c.applyEnv(os.Getenv); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) applyEnv(getenv func(string) string) error {
+	str := func(key string, dst *string) {
+		if v := getenv(key); v != "" {
+			*dst = v
+		}
+	}
+	str("ZETTEL_ROOT", &c.Vault.Root)
+	str("ZETTEL_DB", &c.Index.DB)
+	str("ZETTEL_PARSER_MODE", &c.Parser.Mode)
+	str("TELEGRAM_TOKEN", &c.Telegram.Token)
+	str("ZETTEL_ALLOW_FILE", &c.Telegram.AllowFile)
+	str("OLLAMA_URL", &c.AI.URL) // Legacy names, kept for existing deployments
+	str("OLLAMA_MODEL", &c.AI.Model)
+	str("ZETTEL_AI_BACKEND", &c.AI.Backend)
+	str("ZETTEL_AI_URL", &c.AI.URL)
+	str("ZETTEL_AI_MODEL", &c.AI.Model)
+	str("ZETTEL_AI_EMBED_MODEL", &c.AI.EmbedModel)
+	str("ZETTEL_AI_KEY", &c.AI.APIKey)
+	str("ZETTEL_TZ", &c.Scheduler.Timezone)
+
+	if v := getenv("ZETTEL_ALLOW"); v != "" {
+		c.Telegram.Allow = strings.Split(v, ",")
+	}
+	if v := getenv("ZETTEL_GIT"); v != "" {
+		c.Vault.Git = v == "1" || strings.EqualFold(v, "true")
+	}
+	if v := getenv("ZETTEL_AI_EMBEDDINGS"); v != "" {
+		c.AI.Embeddings = v == "1" || strings.EqualFold(v, "true")
+	}
+
+	var errs []error
+	if v := getenv("ZETTEL_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ZETTEL_WORKERS: %q is not a number", v))
+		}
+		c.Index.Workers = n
+	}
+	if v := getenv("ZETTEL_SYNC_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ZETTEL_SYNC_INTERVAL: %q is not a duration", v))
+		}
+		c.Index.SyncInterval = d
+	}
+	if v := getenv("ZETTEL_AI_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ZETTEL_AI_TIMEOUT: %q is not a duration", v))
+		}
+		c.AI.Timeout = d
+	}
+	if v := getenv("ZETTEL_AI_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ZETTEL_AI_MAX_ATTEMPTS: %q is not a number", v))
+		}
+		c.AI.MaxAttempts = n
+	}
+	if v := getenv("ZETTEL_DAILY_PURGE_HOUR"); v != "" {
+		h, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ZETTEL_DAILY_PURGE_HOUR: %q is not a number", v))
+		}
+		c.Scheduler.DailyPurgeHour = h
+	}
+	return errors.Join(errs...)
+}
+
+var reCategory = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// Validate checks every field and returns all problems at once.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(field, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Vault.Root == "" {
+		fail("vault.root", "required")
+	} else if info, err := os.Stat(c.Vault.Root); err != nil || !info.IsDir() {
+		fail("vault.root", "%q is not a directory", c.Vault.Root)
+	}
+	seen := map[string]bool{}
+	for _, cat := range c.Vault.Categories {
+		if !reCategory.MatchString(cat) {
+			fail("vault.categories", "%q must be a lowercase word (a-z, 0-9, -)", cat)
+		}
+		if seen[cat] {
+			fail("vault.categories", "%q listed twice", cat)
+		}
+		seen[cat] = true
+	}
+	if !seen["idea"] {
+		fail("vault.categories", "must include \"idea\" (the default category)")
+	}
+	if !seen[markdown.TaskTipo] {
+		fail("vault.categories", "must include %q (the notes /work runs on)", markdown.TaskTipo)
+	}
+
+	if c.Index.DB == "" {
+		fail("index.db", "required")
+	}
+	if c.Index.Workers < 1 || c.Index.Workers > 64 {
+		fail("index.workers", "%d out of range 1-64", c.Index.Workers)
+	}
+	if c.Index.SyncInterval < time.Minute {
+		fail("index.sync_interval", "%s is below 1m", c.Index.SyncInterval)
+	}
+
+	if _, err := markdown.ParseMode(c.Parser.Mode); err != nil {
+		fail("parser.mode", "%v", err)
+	}
+	if err := c.Parser.Limits.markdown().Check(); err != nil {
+		fail("parser.limits", "%v", err)
+	}
+
+	if _, err := c.ACL(); err != nil {
+		fail("telegram.allow", "%v", err)
+	}
+
+	if _, err := c.Backend(); err != nil {
+		fail("ai.backend", "%v", err)
+	}
+	if c.AI.Timeout < time.Second {
+		fail("ai.timeout", "%s is below 1s", c.AI.Timeout)
+	}
+	if n := c.AI.MaxAttempts; n < 1 || n > 5 {
+		fail("ai.max_attempts", "%d out of range 1-5", n)
+	}
+
+	if _, err := c.Location(); err != nil {
+		fail("scheduler.timezone", "%v", err)
+	}
+	if h := c.Scheduler.DailyPurgeHour; h < 0 || h > 23 {
+		fail("scheduler.daily_purge_hour", "%d out of range 0-23", h)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// ErrApplied is returned by a second Apply in the same process.
+var ErrApplied = errors.New("config already applied")
+
+// applied guards Apply: its settings are package globals read everywhere.
+var applied atomic.Bool
+
+// Apply installs the parser settings (mode, limits, categories), the doctor
+// layout and the structured answer retry bound process-wide.
+// Start-up only: call it once after Load, before anything parses notes. The
+// settings live in package globals (markdown, index, neural), so a second
+// config in the same process would silently change the first one's
+// behaviour; Apply refuses with ErrApplied instead.
+func (c *Config) Apply() error {
+	if !applied.CompareAndSwap(false, true) {
+		return ErrApplied
+	}
+	markdown.DefaultMode, _ = markdown.ParseMode(c.Parser.Mode)
+	markdown.DefaultLimits = c.Parser.Limits.markdown()
+	markdown.Tipos = c.Vault.Categories
+	index.DefaultLayout = index.LayoutFor(c.Vault.Categories)
+	neural.MaxAttempts = c.AI.MaxAttempts
+	return nil
+}
+
+// ACL merges the allowlist file and the inline entries (inline wins).
+func (c *Config) ACL() (ACL, error) {
+	acl := ACL{}
+	if c.Telegram.AllowFile != "" {
+		fromFile, err := LoadACL(c.Telegram.AllowFile)
+		if err != nil {
+			return nil, err
+		}
+		for id, role := range fromFile {
+			acl[id] = role
+		}
+	}
+	inline, err := ParseACL(strings.Join(c.Telegram.Allow, ","))
+	if err != nil {
+		return nil, err
+	}
+	for id, role := range inline {
+		acl[id] = role
+	}
+	return acl, nil
+}
+
+// Backend builds the configured model backend (shared by bot and indexer).
+func (c *Config) Backend() (neural.Backend, error) {
+	if c.AI.Backend == "" {
+		return nil, fmt.Errorf("required (want %s)", strings.Join(neural.Backends, "|"))
+	}
+	return neural.New(neural.Options{
+		Backend:    c.AI.Backend,
+		URL:        c.AI.URL,
+		Model:      c.AI.Model,
+		EmbedModel: c.AI.EmbedModel,
+		APIKey:     c.AI.APIKey,
+		Timeout:    c.AI.Timeout,
+	})
+}
+
+// EmbeddingKey identifies the embedding model in the vector cache, so
+// switching backend or model re-embeds instead of mixing vector spaces.
+func (c *Config) EmbeddingKey() string {
+	model := c.AI.EmbedModel
+	if model == "" {
+		model = c.AI.Model
+	}
+	return c.AI.Backend + "/" + model
+}
+
+// Location resolves the scheduler's default timezone.
+func (c *Config) Location() (*time.Location, error) {
+	if c.Scheduler.Timezone == "" || c.Scheduler.Timezone == "Local" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(c.Scheduler.Timezone)
+}
+
+func (l Limits) markdown() markdown.Limits {
+	return markdown.Limits{
+		TotalChars:   l.TotalChars,
+		TitleChars:   l.TitleChars,
+		NotasChars:   l.NotasChars,
+		ResumenChars: l.ResumenChars,
+		CuesCount:    l.CuesCount,
+		CueLen:       l.CueLen,
+	}
+}
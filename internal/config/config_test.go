@@ -0,0 +1,182 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+	"github.com/eliseohh/zettelcornelbot/internal/neural"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExampleIsDefault(t *testing.T) {
+	c, err := Load("../../zettel.example.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Default()
+	want.Path = c.Path
+	if c.Index != want.Index || c.Parser != want.Parser || c.AI != want.AI || c.Scheduler != want.Scheduler ||
+		strings.Join(c.Vault.Categories, ",") != strings.Join(want.Vault.Categories, ",") {
+		t.Errorf("zettel.example.toml drifted from Default():\n%+v\n%+v", c, want)
+	}
+}
+
+func TestLoadFileAndEnv(t *testing.T) {
+	root := t.TempDir()
+	allow := writeFile(t, "allow.txt", "# team\n10:reader\n11:owner\n")
+	path := writeFile(t, "zettel.toml", `
+[vault]
+root = "`+root+`"
+categories = ["idea", "paper", "tarea"]
+
+[index]
+workers = 2
+sync_interval = "10m"
+
+[parser.limits]
+total_chars = 3000
+title_chars = 120
+notas_chars = 2000
+resumen_chars = 500
+cues_count = 5
+cue_len = 100
+
+[telegram]
+allow = ["11:reader", "12:reviewer"]
+allow_file = "`+allow+`"
+
+[scheduler]
+timezone = "Europe/Madrid"
+`)
+	t.Setenv("ZETTEL_DB", "/tmp/env.db")
+	t.Setenv("ZETTEL_WORKERS", "8")
+	t.Setenv("OLLAMA_MODEL", "llama3.2")
+	t.Setenv("ZETTEL_AI_BACKEND", "openai")
+	t.Setenv("ZETTEL_AI_MODEL", "qwen")
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Index.DB != "/tmp/env.db" || c.Index.Workers != 8 || c.Index.SyncInterval != 10*time.Minute {
+		t.Errorf("Unexpected index config: %+v", c.Index)
+	}
+	if c.AI.Backend != "openai" || c.AI.Model != "qwen" {
+		t.Errorf("ZETTEL_AI_* should win over the legacy OLLAMA_* names: %+v", c.AI)
+	}
+	if ai, err := c.Backend(); err != nil || ai.(*neural.OpenAI).Model != "qwen" {
+		t.Errorf("Backend() = %#v, %v", ai, err)
+	}
+	if c.Parser.Mode != Default().Parser.Mode {
+		t.Errorf("Unset key should keep its default: %q", c.Parser.Mode)
+	}
+
+	acl, _ := c.ACL()
+	if acl[10] != RoleReader || acl[11] != RoleReader || acl[12] != RoleReviewer {
+		t.Errorf("Unexpected ACL (inline should win over file): %v", acl)
+	}
+	if loc, _ := c.Location(); loc.String() != "Europe/Madrid" {
+		t.Errorf("Unexpected timezone: %v", loc)
+	}
+
+	defer func(mode markdown.Mode, limits markdown.Limits, tipos []string) {
+		markdown.DefaultMode, markdown.DefaultLimits, markdown.Tipos = mode, limits, tipos
+	}(markdown.DefaultMode, markdown.DefaultLimits, markdown.Tipos)
+	if err := c.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if markdown.DefaultLimits.CuesCount != 5 || len(markdown.Tipos) != 3 {
+		t.Errorf("Apply did not install parser settings: %+v %v", markdown.DefaultLimits, markdown.Tipos)
+	}
+
+	// A second config must not silently replace the process-wide settings
+	if err := Default().Apply(); !errors.Is(err, ErrApplied) {
+		t.Errorf("Expected ErrApplied, got %v", err)
+	}
+	if markdown.DefaultLimits.CuesCount != 5 {
+		t.Errorf("Second Apply changed the limits: %+v", markdown.DefaultLimits)
+	}
+}
+
+func TestValidateReportsEverything(t *testing.T) {
+	path := writeFile(t, "bad.toml", `
+[vault]
+root = "/does/not/exist"
+categories = ["paper", "Bad Name"]
+
+[index]
+workers = 0
+
+[parser]
+mode = "loose"
+
+[parser.limits]
+total_chars = 5000
+
+[telegram]
+allow = ["1:admin"]
+
+[ai]
+backend = "gpt"
+timeout = "10ms"
+max_attempts = 0
+
+[scheduler]
+timezone = "Mars/Olympus"
+daily_purge_hour = 24
+`)
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, want := range []string{
+		"vault.root:", "vault.categories: \"Bad Name\"", "must include \"idea\"", "must include \"tarea\"", "index.workers:",
+		"parser.mode:", "parser.limits: total_chars = 5000", "telegram.allow:", "ai.backend: unknown backend \"gpt\"",
+		"ai.timeout:", "ai.max_attempts: 0 out of range", "scheduler.timezone:",
+		"scheduler.daily_purge_hour:",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Missing %q in:\n%v", want, err)
+		}
+	}
+
+	if _, err := Load(writeFile(t, "typo.toml", "[vault]\nrot = \".\"\n")); err == nil || !strings.Contains(err.Error(), "unknown keys") {
+		t.Errorf("Expected unknown key error, got %v", err)
+	}
+
+	t.Setenv("ZETTEL_WORKERS", "many")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "ZETTEL_WORKERS") {
+		t.Errorf("Expected env parse error, got %v", err)
+	}
+}
+
+func TestParseACL(t *testing.T) {
+	acl, err := ParseACL("1:owner, 2:Reader # comment\n-100:reviewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acl[1] != RoleOwner || acl[2] != RoleReader || acl[-100] != RoleReviewer {
+		t.Errorf("Unexpected ACL: %v", acl)
+	}
+	if acl.Role(5, -100) != RoleReviewer || acl.Role(1, -100) != RoleOwner || acl.Role(5, 6) != "" {
+		t.Error("User entries should win over chat entries")
+	}
+	for _, bad := range []string{"1", "x:owner", "1:admin"} {
+		if _, err := ParseACL(bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
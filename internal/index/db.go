@@ -3,13 +3,14 @@ package index
 import (
 	"database/sql"
 	"fmt"
-	"os"
+	"path/filepath"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type DB struct {
 	*sql.DB
+	fts bool // FTS5 available (build with -tags sqlite_fts5)
 }
 
 func NewDB(dbPath string) (*DB, error) {
@@ -23,31 +24,122 @@ func NewDB(dbPath string) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping db: %w", err)
 	}
 
-	return &DB{db}, nil
+	return &DB{DB: db}, nil
 }
 
-func (d *DB) InitSchema(schemaContent string) error {
-	_, err := d.Exec(schemaContent)
+// LookupPath returns the vault-relative path indexed for a note ID.
+func (d *DB) LookupPath(id string) (string, error) {
+	var path string
+	if err := d.QueryRow("SELECT path FROM nodes WHERE id = ?", id).Scan(&path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Duplicates returns the paths left out of the index because the note at
+// ownerPath already holds their ID (see DuplicateIDError).
+func (d *DB) Duplicates(ownerPath string) ([]string, error) {
+	rows, err := d.Query("SELECT path FROM parse_errors WHERE duplicate_of = ? ORDER BY path", ownerPath)
 	if err != nil {
-		return fmt.Errorf("failed to apply schema: %w", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
+
+// NodeHash returns the content hash indexed for a vault-relative path ("" if not indexed).
+func (d *DB) NodeHash(relPath string) (string, error) {
+	var hash string
+	err := d.QueryRow("SELECT hash FROM nodes WHERE path = ?", relPath).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
 	}
-	return nil
+	return hash, err
 }
 
-// Helper to reset the index completely (Determinism principle)
-func (d *DB) Nuke() error {
-	_, err := d.Exec(`
-		DROP TABLE IF EXISTS edges;
-		DROP TABLE IF EXISTS tags;
-		DROP TABLE IF EXISTS nodes;
-	`)
-	return err
+// ParseFailed reports whether the file at a vault-relative path failed to index
+// on the last Sync. Its nodes row, if any, still describes an older version.
+func (d *DB) ParseFailed(relPath string) (bool, error) {
+	var n int
+	err := d.QueryRow("SELECT COUNT(*) FROM parse_errors WHERE path = ?", relPath).Scan(&n)
+	return n > 0, err
 }
 
-func ReadSchemaFile(path string) (string, error) {
-	b, err := os.ReadFile(path)
+// Cues returns the indexed cues of a note in document order.
+func (d *DB) Cues(id string) ([]string, error) {
+	rows, err := d.Query("SELECT text FROM cues WHERE node_id = ? ORDER BY position", id)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cues []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, err
+		}
+		cues = append(cues, c)
+	}
+	return cues, rows.Err()
+}
+
+// Section returns the indexed body of a Cornell section ("Notas", "Resumen").
+func (d *DB) Section(id, name string) (string, error) {
+	var content string
+	err := d.QueryRow("SELECT content FROM sections WHERE node_id = ? AND name = ?", id, name).Scan(&content)
+	return content, err
+}
+
+// PathsUnder returns the indexed paths inside a vault-relative folder.
+func (d *DB) PathsUnder(dir string) ([]string, error) {
+	prefix := filepath.Clean(dir) + string(filepath.Separator)
+	rows, err := d.Query("SELECT path FROM nodes WHERE substr(path, 1, ?) = ?", len(prefix), prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
+
+// ClearIndex empties every table derived from the vault (Determinism principle:
+// a full Sync rebuilds them). State that is not in the Markdown survives:
+// review cards, jobs, sessions, /daily entries, the op and audit logs.
+func (d *DB) ClearIndex() error {
+	tx, err := d.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// edges, tags, cues and sections cascade from nodes
+	for _, table := range []string{"nodes", "parse_errors"} {
+		if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+			return fmt.Errorf("clear %s: %w", table, err)
+		}
+	}
+	if d.fts {
+		if _, err := tx.Exec("DELETE FROM notes_fts"); err != nil {
+			return fmt.Errorf("clear notes_fts: %w", err)
+		}
 	}
-	return string(b), nil
+	return tx.Commit()
 }
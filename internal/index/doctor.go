@@ -0,0 +1,210 @@
+package index
+
+import (
+	"database/sql"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+)
+
+// DefaultLayout maps each Tipo to the top-level folder its notes live in ("" = vault root).
+var DefaultLayout = LayoutFor(markdown.Tipos)
+
+// LayoutFor builds the bot's folder layout for a set of categories:
+// "idea" at the vault root, every other category in a folder of the same name.
+func LayoutFor(categories []string) map[string]string {
+	layout := make(map[string]string, len(categories))
+	for _, c := range categories {
+		layout[c] = c
+	}
+	if _, ok := layout["idea"]; ok {
+		layout["idea"] = ""
+	}
+	return layout
+}
+
+// DanglingLink is a [[target]] with no note behind it.
+type DanglingLink struct {
+	Source string
+	Target string
+}
+
+// TipoMismatch is a note whose Tipo disagrees with the folder it lives in.
+type TipoMismatch struct {
+	ID     string
+	Path   string
+	Tipo   string
+	Folder string // Expected folder for Tipo ("" = root)
+	Known  bool   // False when Tipo is not in the layout at all
+}
+
+// ParseError is a file that failed ParseFile during the last Sync.
+type ParseError struct {
+	Path  string
+	Error string
+}
+
+// DuplicateID is a note left out of the index because another path already owns its ID.
+type DuplicateID struct {
+	ID    string
+	Path  string
+	Owner string // Path indexed under ID
+}
+
+// Report is the vault integrity check. Every list is sorted so the rendering is stable.
+type Report struct {
+	Dangling     []DanglingLink
+	Orphans      []string // Note IDs with no incoming or outgoing edges
+	TipoMismatch []TipoMismatch
+	ParseErrors  []ParseError
+	DuplicateIDs []DuplicateID
+}
+
+// Clean reports whether no issue was found.
+func (r *Report) Clean() bool {
+	return len(r.Dangling) == 0 && len(r.Orphans) == 0 && len(r.TipoMismatch) == 0 && len(r.ParseErrors) == 0 &&
+		len(r.DuplicateIDs) == 0
+}
+
+// Doctor checks the index for integrity issues. layout is the Tipo → folder
+// mapping (see DefaultLayout); notes in folders outside it are not checked.
+func (d *DB) Doctor(layout map[string]string) (*Report, error) {
+	r := &Report{}
+
+	rows, err := d.Query(`
+		SELECT e.source_id, e.target_id FROM edges e
+		LEFT JOIN nodes n ON n.id = e.target_id
+		WHERE n.id IS NULL
+		ORDER BY e.source_id, e.target_id`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var l DanglingLink
+		if err := rows.Scan(&l.Source, &l.Target); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		r.Dangling = append(r.Dangling, l)
+	}
+	rows.Close()
+
+	// Self-links don't count as a connection; a dangling outlink does (the note links somewhere)
+	rows, err = d.Query(`
+		SELECT n.id FROM nodes n
+		WHERE NOT EXISTS (SELECT 1 FROM edges e WHERE e.source_id = n.id AND e.target_id != n.id)
+		  AND NOT EXISTS (SELECT 1 FROM edges e WHERE e.target_id = n.id AND e.source_id != n.id)
+		ORDER BY n.id`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		r.Orphans = append(r.Orphans, id)
+	}
+	rows.Close()
+
+	folders := map[string]bool{}
+	for _, f := range layout {
+		folders[f] = true
+	}
+	rows, err = d.Query(`
+		SELECT n.id, n.path, t.tag FROM nodes n
+		JOIN tags t ON t.node_id = n.id
+		ORDER BY n.path`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var m TipoMismatch
+		if err := rows.Scan(&m.ID, &m.Path, &m.Tipo); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		folder := topFolder(m.Path)
+		if !folders[folder] {
+			continue
+		}
+		want, ok := layout[m.Tipo]
+		if ok && want == folder {
+			continue
+		}
+		m.Folder, m.Known = want, ok
+		r.TipoMismatch = append(r.TipoMismatch, m)
+	}
+	rows.Close()
+
+	rows, err = d.Query("SELECT path, error, duplicate_of FROM parse_errors ORDER BY path")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p ParseError
+		var owner sql.NullString
+		if err := rows.Scan(&p.Path, &p.Error, &owner); err != nil {
+			return nil, err
+		}
+		if owner.Valid {
+			id := strings.TrimSuffix(path.Base(strings.ReplaceAll(p.Path, "\\", "/")), path.Ext(p.Path))
+			r.DuplicateIDs = append(r.DuplicateIDs, DuplicateID{ID: id, Path: p.Path, Owner: owner.String})
+			continue
+		}
+		r.ParseErrors = append(r.ParseErrors, p)
+	}
+	return r, rows.Err()
+}
+
+// topFolder returns the first path component of a vault-relative path ("" for root files).
+func topFolder(rel string) string {
+	dir := path.Dir(strings.ReplaceAll(rel, "\\", "/"))
+	if dir == "." {
+		return ""
+	}
+	first, _, _ := strings.Cut(dir, "/")
+	return first
+}
+
+// String renders the report as plain text. Output only depends on the index
+// contents, so it can be diffed between runs.
+func (r *Report) String() string {
+	sb := strings.Builder{}
+	section := func(title string, n int) {
+		sb.WriteString(fmt.Sprintf("%s (%d)\n", title, n))
+	}
+
+	section("Dangling links", len(r.Dangling))
+	for _, l := range r.Dangling {
+		sb.WriteString(fmt.Sprintf("  %s -> [[%s]]\n", l.Source, l.Target))
+	}
+	section("Orphan notes", len(r.Orphans))
+	for _, id := range r.Orphans {
+		sb.WriteString(fmt.Sprintf("  %s\n", id))
+	}
+	section("Tipo/folder mismatches", len(r.TipoMismatch))
+	for _, m := range r.TipoMismatch {
+		switch {
+		case !m.Known:
+			sb.WriteString(fmt.Sprintf("  %s: unknown Tipo '%s'\n", m.Path, m.Tipo))
+		case m.Folder == "":
+			sb.WriteString(fmt.Sprintf("  %s: Tipo '%s' belongs in the vault root\n", m.Path, m.Tipo))
+		default:
+			sb.WriteString(fmt.Sprintf("  %s: Tipo '%s' belongs in %s/\n", m.Path, m.Tipo, m.Folder))
+		}
+	}
+	section("Parse errors", len(r.ParseErrors))
+	for _, p := range r.ParseErrors {
+		sb.WriteString(fmt.Sprintf("  %s: %s\n", p.Path, p.Error))
+	}
+	section("Duplicate IDs", len(r.DuplicateIDs))
+	for _, dup := range r.DuplicateIDs {
+		sb.WriteString(fmt.Sprintf("  %s: ID '%s' already indexed at %s\n", dup.Path, dup.ID, dup.Owner))
+	}
+	return sb.String()
+}
@@ -0,0 +1,84 @@
+package index
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+)
+
+func TestDoctor(t *testing.T) {
+	db := newTestDB(t)
+	vault := t.TempDir()
+
+	write := func(rel, tipo string, links ...string) {
+		content := "# Note\nFecha: 2024-02-02\nTipo: " + tipo + "\n\n## Notas\n\n## Cues\n\n## Resumen\n\n## Enlaces\n"
+		for _, l := range links {
+			content += "- [[" + l + "]]\n"
+		}
+		full := filepath.Join(vault, rel)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, []byte(content), 0644)
+	}
+	write("a.md", "idea", "b", "ghost")
+	write("b.md", "idea")
+	write("lonely.md", "idea")
+	write("tarea/misplaced.md", "libro", "a")
+	write("libro/odd.md", "poema", "a")
+	write("archivo/2023/old.md", "tarea", "a") // Outside the layout: not checked
+	write("libro/b.md", "libro")               // Same ID as b.md: left out of the index
+	os.WriteFile(filepath.Join(vault, "broken.md"), []byte("# Broken\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\n\n## Cues\n- no mark\n\n## Resumen\n\n## Enlaces\n"), 0644)
+
+	idx := NewIndexer(db)
+	idx.Out = io.Discard
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := db.Doctor(DefaultLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Clean() {
+		t.Fatal("Expected issues")
+	}
+
+	want := `Dangling links (1)
+  a -> [[ghost]]
+Orphan notes (1)
+  lonely
+Tipo/folder mismatches (1)
+  tarea/misplaced.md: Tipo 'libro' belongs in libro/
+Parse errors (2)
+  broken.md: validation error: 8:9 CueMissingQuestionMark: cue 'no mark' must end with '?'
+  libro/odd.md: validation error: 3:7-3:11 InvalidTipo: Tipo 'poema' is not one of idea|estudio|libro|tarea
+Duplicate IDs (1)
+  libro/b.md: ID 'b' already indexed at b.md
+`
+	if got := r.String(); got != want {
+		t.Errorf("Unexpected report:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Lenient vaults index unknown Tipos; doctor still flags them
+	markdown.DefaultMode = markdown.Lenient
+	defer func() { markdown.DefaultMode = markdown.Strict }()
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+	r, _ = db.Doctor(DefaultLayout)
+	if len(r.TipoMismatch) != 2 || r.TipoMismatch[0].Path != "libro/odd.md" || r.TipoMismatch[0].Known {
+		t.Errorf("Expected unknown Tipo mismatch: %+v", r.TipoMismatch)
+	}
+
+	// Fixing the file clears its parse error on the next Sync
+	os.WriteFile(filepath.Join(vault, "broken.md"), []byte("# Fixed\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\n\n## Cues\n\n## Resumen\n\n## Enlaces\n- [[lonely]]\n"), 0644)
+	if err := idx.SyncPaths(vault, []string{"broken.md"}); err != nil {
+		t.Fatal(err)
+	}
+	r, _ = db.Doctor(DefaultLayout)
+	if len(r.ParseErrors) != 0 || len(r.Orphans) != 0 {
+		t.Errorf("Expected parse error and orphan gone: %+v", r)
+	}
+}
@@ -0,0 +1,263 @@
+package index
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EmbedModel turns text into a vector (neural.Backend satisfies it).
+type EmbedModel interface {
+	Embed(text string) ([]float32, error)
+}
+
+// ErrNotEmbedded means the note is indexed but its vector is not computed yet.
+var ErrNotEmbedded = errors.New("note not embedded yet")
+
+// Embedder fills the embeddings table in the background so Sync never waits
+// on the model. Vectors are cached by note hash: each pass only embeds nodes
+// whose current hash has no vector for Key.
+type Embedder struct {
+	db    *DB
+	model EmbedModel
+	Key   string    // Model identity in the cache, e.g. "ollama/nomic-embed-text"
+	Out   io.Writer // Progress log (default os.Stdout)
+
+	mu   sync.Mutex // One pass at a time
+	kick chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewEmbedder(db *DB, model EmbedModel, key string) *Embedder {
+	return &Embedder{
+		db:    db,
+		model: model,
+		Key:   key,
+		Out:   os.Stdout,
+		kick:  make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// Match is a note ranked by cosine similarity.
+type Match struct {
+	ID    string
+	Path  string
+	Title string
+	Score float64 // Cosine similarity, 1 = same direction
+}
+
+// Start runs a pass now and another after every Notify, until Close.
+func (e *Embedder) Start() {
+	e.Notify()
+	go func() {
+		defer close(e.done)
+		for {
+			select {
+			case <-e.stop:
+				return
+			case <-e.kick:
+				if n, err := e.Run(); err != nil {
+					fmt.Fprintf(e.Out, "⚠️ Embedding stopped after %d note(s): %v\n", n, err)
+				} else if n > 0 {
+					fmt.Fprintf(e.Out, "[≈] Embedded %d note(s)\n", n)
+				}
+			}
+		}
+	}()
+}
+
+// Notify asks for a pass without blocking; requests made during a pass coalesce into one.
+func (e *Embedder) Notify() {
+	select {
+	case e.kick <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops the loop started by Start, waiting for the current pass.
+func (e *Embedder) Close() {
+	close(e.stop)
+	<-e.done
+}
+
+type pendingNote struct {
+	hash string
+	path string // One of the notes with this hash, for logs
+	text string
+}
+
+// Run embeds every indexed note whose hash has no vector yet and returns how
+// many it stored. A note the model rejects is logged and skipped (it is
+// retried on the next pass); only a connection or DB failure ends the pass.
+func (e *Embedder) Run() (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rows, err := e.db.Query(`SELECT n.hash, MIN(n.path), COALESCE(n.title, ''),
+			COALESCE((SELECT content FROM sections WHERE node_id = n.id AND name = 'Notas'), ''),
+			COALESCE((SELECT content FROM sections WHERE node_id = n.id AND name = 'Resumen'), ''),
+			COALESCE((SELECT group_concat(text, char(10)) FROM cues WHERE node_id = n.id), '')
+		FROM nodes n
+		WHERE NOT EXISTS (SELECT 1 FROM embeddings e WHERE e.hash = n.hash AND e.model = ?)
+		GROUP BY n.hash`, e.Key)
+	if err != nil {
+		return 0, err
+	}
+	var pending []pendingNote
+	for rows.Next() {
+		var hash, path, title, notas, resumen, cues string
+		if err := rows.Scan(&hash, &path, &title, &notas, &resumen, &cues); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, pendingNote{hash, path, embedText(title, notas, resumen, cues)})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	stored := 0
+	for _, p := range pending {
+		vec, err := e.model.Embed(p.text)
+		var connErr *url.Error
+		if errors.As(err, &connErr) {
+			return stored, err // The backend is unreachable: every other note would fail too
+		}
+		if err != nil {
+			fmt.Fprintf(e.Out, "⚠️ Embedding %s skipped: %v\n", p.path, err)
+			continue
+		}
+		_, err = e.db.Exec(`INSERT OR REPLACE INTO embeddings (hash, model, dims, vector, created_at) VALUES (?, ?, ?, ?, ?)`,
+			p.hash, e.Key, len(vec), encodeVector(vec), time.Now().Unix())
+		if err != nil {
+			return stored, err
+		}
+		stored++
+	}
+	return stored, nil
+}
+
+// embedText is what gets embedded: the note's title and Cornell sections.
+func embedText(title, notas, resumen, cues string) string {
+	parts := []string{}
+	for _, s := range []string{title, notas, resumen, cues} {
+		if s = strings.TrimSpace(s); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// SimilarTo ranks the other embedded notes by similarity to the note at relPath.
+func (e *Embedder) SimilarTo(relPath string, k int) ([]Match, error) {
+	var blob []byte
+	err := e.db.QueryRow(`SELECT e.vector FROM nodes n JOIN embeddings e ON e.hash = n.hash AND e.model = ?
+		WHERE n.path = ?`, e.Key, relPath).Scan(&blob)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotEmbedded
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e.nearest(decodeVector(blob), k, relPath)
+}
+
+// Search embeds a free-text query and ranks every embedded note against it.
+func (e *Embedder) Search(query string, k int) ([]Match, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, ErrEmptyQuery
+	}
+	vec, err := e.model.Embed(query)
+	if err != nil {
+		return nil, err
+	}
+	return e.nearest(vec, k, "")
+}
+
+// nearest is a brute-force scan: fine for personal vaults (thousands of notes).
+func (e *Embedder) nearest(query []float32, k int, exclude string) ([]Match, error) {
+	rows, err := e.db.Query(`SELECT n.id, n.path, COALESCE(n.title, ''), e.vector
+		FROM nodes n JOIN embeddings e ON e.hash = n.hash AND e.model = ?
+		WHERE n.path != ?`, e.Key, exclude)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		var m Match
+		var blob []byte
+		if err := rows.Scan(&m.ID, &m.Path, &m.Title, &blob); err != nil {
+			return nil, err
+		}
+		vec := decodeVector(blob)
+		if len(vec) != len(query) {
+			continue
+		}
+		m.Score = cosine(query, vec)
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+// PruneEmbeddings drops vectors whose hash no longer belongs to any note.
+func (d *DB) PruneEmbeddings() error {
+	_, err := d.Exec("DELETE FROM embeddings WHERE hash NOT IN (SELECT hash FROM nodes)")
+	return err
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+func encodeVector(vec []float32) []byte {
+	buf := make([]byte, 4*len(vec))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	vec := make([]float32, len(buf)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return vec
+}
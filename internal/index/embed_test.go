@@ -0,0 +1,169 @@
+package index
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eliseohh/zettelcornelbot/internal/neural"
+)
+
+// countingModel records how many texts reach the model.
+type countingModel struct {
+	neural.Fake
+	calls atomic.Int32
+}
+
+func (m *countingModel) Embed(text string) ([]float32, error) {
+	m.calls.Add(1)
+	return m.Fake.Embed(text)
+}
+
+// pickyModel rejects texts containing reject, like a model whose context is too small.
+type pickyModel struct {
+	neural.Fake
+	reject string
+	down   bool
+}
+
+func (m *pickyModel) Embed(text string) ([]float32, error) {
+	if m.down {
+		return nil, fmt.Errorf("ollama connection failed: %w", &url.Error{Op: "Post", URL: "http://localhost:11434", Err: errors.New("connection refused")})
+	}
+	if strings.Contains(text, m.reject) {
+		return nil, errors.New("ollama error: 500: input length exceeds context")
+	}
+	return m.Fake.Embed(text)
+}
+
+func writeEmbedNote(t *testing.T, vault, id, notas string) {
+	t.Helper()
+	content := "# " + id + "\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\n" + notas + "\n\n## Cues\n\n## Resumen\n\n## Enlaces\n"
+	if err := os.WriteFile(filepath.Join(vault, id+".md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEmbedder(t *testing.T) {
+	db := newTestDB(t)
+	vault := t.TempDir()
+	writeEmbedNote(t, vault, "cats", "Cats purr and sleep in the sun.")
+	writeEmbedNote(t, vault, "kittens", "Young cats sleep most of the day and purr.")
+	writeEmbedNote(t, vault, "taxes", "Quarterly tax filing deadline for freelancers.")
+
+	model := &countingModel{}
+	emb := NewEmbedder(db, model, "fake/test")
+	emb.Out = io.Discard
+	idx := NewIndexer(db)
+	idx.Out = io.Discard
+	idx.Embedder = emb
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := emb.Run(); err != nil || n != 3 {
+		t.Fatalf("First pass: embedded %d, %v", n, err)
+	}
+	if n, _ := emb.Run(); n != 0 {
+		t.Errorf("Unchanged notes were re-embedded: %d", n)
+	}
+
+	matches, err := emb.SimilarTo("cats.md", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 || matches[0].ID != "kittens" || matches[0].Score <= matches[1].Score {
+		t.Errorf("Unexpected ranking: %+v", matches)
+	}
+	if matches, _ := emb.Search("tax deadline", 1); len(matches) != 1 || matches[0].ID != "taxes" {
+		t.Errorf("Unexpected search result: %+v", matches)
+	}
+
+	// Only the edited note goes back to the model; a rebuild keeps the cache
+	writeEmbedNote(t, vault, "taxes", "Quarterly tax filing, now with receipts.")
+	idx.Sync(vault)
+	if err := idx.Rebuild(vault); err != nil {
+		t.Fatal(err)
+	}
+	before := model.calls.Load()
+	if n, _ := emb.Run(); n != 1 || model.calls.Load() != before+1 {
+		t.Errorf("Expected exactly the edited note to be embedded, got %d", n)
+	}
+
+	os.Remove(filepath.Join(vault, "kittens.md"))
+	idx.Sync(vault)
+	var n int
+	db.QueryRow("SELECT COUNT(*) FROM embeddings").Scan(&n)
+	if n != 2 {
+		t.Errorf("Expected stale vectors pruned, %d left", n)
+	}
+
+	writeEmbedNote(t, vault, "dogs", "Dogs bark.")
+	idx.Sync(vault)
+	if _, err := emb.SimilarTo("dogs.md", 5); err != ErrNotEmbedded {
+		t.Errorf("Expected ErrNotEmbedded before the pass, got %v", err)
+	}
+}
+
+func TestEmbedderRunsInBackground(t *testing.T) {
+	db := newTestDB(t)
+	vault := t.TempDir()
+	writeEmbedNote(t, vault, "one", "First note.")
+
+	emb := NewEmbedder(db, &neural.Fake{}, "fake/test")
+	emb.Out = io.Discard
+	idx := NewIndexer(db)
+	idx.Out = io.Discard
+	idx.Embedder = emb
+	emb.Start()
+	defer emb.Close()
+
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "background embedding", func() bool {
+		_, err := emb.SimilarTo("one.md", 1)
+		return err == nil
+	})
+}
+
+func TestEmbedderSkipsRejectedNotes(t *testing.T) {
+	db := newTestDB(t)
+	vault := t.TempDir()
+	for _, id := range []string{"a", "b", "c", "d"} {
+		writeEmbedNote(t, vault, id, "Plain note "+id+".")
+	}
+	writeEmbedNote(t, vault, "huge", "poison")
+
+	idx := NewIndexer(db)
+	idx.Out = io.Discard
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+
+	model := &pickyModel{reject: "poison", down: true}
+	emb := NewEmbedder(db, model, "fake/test")
+	var log bytes.Buffer
+	emb.Out = &log
+	if n, err := emb.Run(); err == nil || n != 0 {
+		t.Errorf("An unreachable backend should end the pass: %d, %v", n, err)
+	}
+
+	model.down = false
+	if n, err := emb.Run(); err != nil || n != 4 {
+		t.Errorf("Expected every other note embedded: %d, %v", n, err)
+	}
+	if !strings.Contains(log.String(), "huge.md skipped") {
+		t.Errorf("Rejected note not logged: %q", log.String())
+	}
+	if _, err := emb.SimilarTo("huge.md", 3); err != ErrNotEmbedded {
+		t.Errorf("Expected the rejected note still pending, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package index
+
+// ExportedNote is one note in `zettel export` output: the index's view of
+// the vault (metadata and graph, not the Markdown body).
+type ExportedNote struct {
+	ID    string   `json:"id"`
+	Path  string   `json:"path"`
+	Title string   `json:"title"`
+	Tipo  string   `json:"tipo"`
+	Links []string `json:"links"`
+	Cues  []string `json:"cues"`
+}
+
+// Export returns every indexed note ordered by ID.
+func (d *DB) Export() ([]ExportedNote, error) {
+	rows, err := d.Query(`
+		SELECT n.id, n.path, COALESCE(n.title, ''), COALESCE(MIN(t.tag), '')
+		FROM nodes n
+		LEFT JOIN tags t ON t.node_id = n.id
+		GROUP BY n.id
+		ORDER BY n.id`)
+	if err != nil {
+		return nil, err
+	}
+	var notes []ExportedNote
+	for rows.Next() {
+		var n ExportedNote
+		if err := rows.Scan(&n.ID, &n.Path, &n.Title, &n.Tipo); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range notes {
+		n := &notes[i]
+		links, err := d.Outlinks(n.ID)
+		if err != nil {
+			return nil, err
+		}
+		n.Links = []string{}
+		for _, l := range links {
+			n.Links = append(n.Links, l.ID)
+		}
+		if n.Cues, err = d.Cues(n.ID); err != nil {
+			return nil, err
+		}
+		if n.Cues == nil {
+			n.Cues = []string{}
+		}
+	}
+	return notes, nil
+}
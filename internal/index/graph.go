@@ -0,0 +1,47 @@
+package index
+
+// Link is one end of an edge, resolved against nodes.
+type Link struct {
+	ID       string
+	Title    string // Empty when dangling
+	Path     string // Empty when dangling
+	Dangling bool   // Target not present in the index
+}
+
+// Outlinks returns the notes id links to. Targets missing from the index are marked Dangling.
+func (d *DB) Outlinks(id string) ([]Link, error) {
+	return d.links(`
+		SELECT DISTINCT e.target_id, COALESCE(n.title, ''), COALESCE(n.path, ''), n.id IS NULL
+		FROM edges e
+		LEFT JOIN nodes n ON n.id = e.target_id
+		WHERE e.source_id = ?
+		ORDER BY e.target_id`, id)
+}
+
+// Backlinks returns the notes that link to id.
+func (d *DB) Backlinks(id string) ([]Link, error) {
+	return d.links(`
+		SELECT DISTINCT n.id, COALESCE(n.title, ''), n.path, 0
+		FROM edges e
+		JOIN nodes n ON n.id = e.source_id
+		WHERE e.target_id = ? AND e.source_id != e.target_id
+		ORDER BY n.id`, id)
+}
+
+func (d *DB) links(query, id string) ([]Link, error) {
+	rows, err := d.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []Link
+	for rows.Next() {
+		var l Link
+		if err := rows.Scan(&l.ID, &l.Title, &l.Path, &l.Dangling); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
@@ -0,0 +1,97 @@
+package index
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	db := newTestDB(t)
+	vault := t.TempDir()
+
+	write := func(id, title string, links ...string) {
+		content := "# " + title + "\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\n\n## Cues\n\n## Resumen\n\n## Enlaces\n"
+		for _, l := range links {
+			content += "- [[" + l + "]]\n"
+		}
+		os.WriteFile(filepath.Join(vault, id+".md"), []byte(content), 0644)
+	}
+	write("a", "Alpha", "b", "ghost")
+	write("b", "Beta", "a")
+	write("c", "Gamma", "a", "a")
+
+	if err := NewIndexer(db).Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := db.Outlinks("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || out[0] != (Link{ID: "b", Title: "Beta", Path: "b.md"}) || out[1] != (Link{ID: "ghost", Dangling: true}) {
+		t.Errorf("Unexpected outlinks: %+v", out)
+	}
+
+	back, err := db.Backlinks("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(back) != 2 || back[0].ID != "b" || back[1].ID != "c" || back[1].Title != "Gamma" {
+		t.Errorf("Unexpected backlinks: %+v", back)
+	}
+
+	// Dangling targets still know who points at them
+	if back, _ := db.Backlinks("ghost"); len(back) != 1 || back[0].ID != "a" {
+		t.Errorf("Unexpected ghost backlinks: %+v", back)
+	}
+}
+
+func TestSuggestLinks(t *testing.T) {
+	db := newTestDB(t)
+	vault := t.TempDir()
+	write := func(id, notas string, links ...string) {
+		content := "# " + id + "\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\n" + notas + "\n\n## Cues\n\n## Resumen\n\n## Enlaces\n"
+		for _, l := range links {
+			content += "- [[" + l + "]]\n"
+		}
+		os.WriteFile(filepath.Join(vault, id+".md"), []byte(content), 0644)
+	}
+	write("src", "Spaced repetition schedules reviews.", "hub")
+	write("hub", "Learning index.")
+	write("peer", "Repetition schedules beat cramming.")
+	write("fan", "Unrelated words entirely.", "src")
+	write("sibling", "Nothing in common.", "hub")
+	write("island", "Completely different topic.")
+	idx := NewIndexer(db)
+	idx.Out = io.Discard
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.SuggestLinks("src", []Match{{ID: "island", Score: 0.9}}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := map[string]LinkSuggestion{}
+	for _, s := range got {
+		byID[s.ID] = s
+	}
+	if _, ok := byID["hub"]; ok {
+		t.Error("Already linked notes must not be suggested")
+	}
+	if len(got) != 4 || got[0].ID != "island" {
+		t.Errorf("Expected 4 suggestions led by the embedding match: %+v", got)
+	}
+	if s := byID["peer"]; len(s.SharedTerms) != 2 || s.Signals() != "shared: repetition, schedules" {
+		t.Errorf("Unexpected shared terms: %+v (%s)", s.SharedTerms, s.Signals())
+	}
+	if !byID["fan"].Backlink || len(byID["sibling"].Via) != 1 || byID["sibling"].Via[0] != "hub" {
+		t.Errorf("Graph signals missing: %+v", got)
+	}
+
+	if got, _ := db.SuggestLinks("src", nil, 1); len(got) != 1 {
+		t.Errorf("Expected k to cap the result: %+v", got)
+	}
+}
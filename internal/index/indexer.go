@@ -3,6 +3,7 @@ package index
 import (
 	"crypto/sha256"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -10,16 +11,39 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/eliseohh/zettelcornelbot/internal/markdown"
 )
 
 type Indexer struct {
 	db *DB
+	mu sync.Mutex // Serializes Sync/SyncPaths (SQLite single writer)
+
+	Out     io.Writer // Progress log (default os.Stdout)
+	Workers int       // Parse/hash goroutines (default DefaultWorkers)
+
+	// OnChange, if set, receives the vault-relative paths that a Sync/SyncPaths
+	// call found new, changed, unparsable or removed (e.g. to version them).
+	OnChange func(paths []string)
+	changed  []string // Batch being collected, guarded by mu
+
+	// Embedder, if set, is woken after every sync to embed new and changed notes.
+	Embedder *Embedder
+
+	// Now stamps new review cards as due (default time.Now).
+	Now func() time.Time
 }
 
+// DefaultWorkers is the size of the hashing/parsing worker pool.
+const DefaultWorkers = 4
+
 func NewIndexer(db *DB) *Indexer {
-	return &Indexer{db: db}
+	return &Indexer{db: db, Out: os.Stdout, Workers: DefaultWorkers, Now: time.Now}
+}
+
+func (idx *Indexer) logf(format string, args ...interface{}) {
+	fmt.Fprintf(idx.Out, format, args...)
 }
 
 // Concurrency structures
@@ -37,28 +61,44 @@ type scanResult struct {
 	Changed bool
 }
 
+// DuplicateIDError is a note whose basename (its ID) is already indexed at
+// another path that still exists. The first note keeps the ID; this one is
+// recorded in parse_errors until one of them is renamed.
+type DuplicateIDError struct {
+	ID    string
+	Owner string // Path that holds the ID
+}
+
+func (e *DuplicateIDError) Error() string {
+	return fmt.Sprintf("duplicate note ID %q: already indexed at %s", e.ID, e.Owner)
+}
+
 // Sync walks the directory with a Worker Pool pattern.
+// It is the full consistency check; the Watcher covers incremental changes.
 func (idx *Indexer) Sync(rootDir string) error {
-	fmt.Printf("Starting Sync for %s (Goroutines)...\n", rootDir)
-
-	// Channels
-	jobs := make(chan scanJob, 100)
-	results := make(chan scanResult, 100)
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.sync(rootDir)
+}
 
-	validPaths := make(map[string]bool)
-	var wg sync.WaitGroup
+// Rebuild empties the derived tables and reindexes the whole vault from scratch.
+func (idx *Indexer) Rebuild(rootDir string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
 
-	// 1. Worker Pool (4 workers)
-	numWorkers := 4
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			idx.worker(jobs, results)
-		}()
+	idx.logf("Rebuilding index for %s...\n", rootDir)
+	if err := idx.db.ClearIndex(); err != nil {
+		return fmt.Errorf("rebuild: %w", err)
 	}
+	return idx.sync(rootDir)
+}
 
-	// 2. Walker (Producer)
+func (idx *Indexer) sync(rootDir string) error {
+	idx.logf("Starting Sync for %s (Goroutines)...\n", rootDir)
+
+	jobs := make(chan scanJob, 100)
+
+	// Walker (Producer)
 	go func() {
 		defer close(jobs)
 		filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
@@ -71,7 +111,7 @@ func (idx *Indexer) Sync(rootDir string) error {
 				}
 				return nil
 			}
-			if !strings.HasSuffix(strings.ToLower(d.Name()), ".md") {
+			if !isNoteFile(d.Name()) {
 				return nil
 			}
 
@@ -85,36 +125,133 @@ func (idx *Indexer) Sync(rootDir string) error {
 		})
 	}()
 
-	// 3. Closer Goroutine
+	defer idx.flushChanges()
+	validPaths, err := idx.process(rootDir, jobs, true)
+	if err != nil {
+		return err
+	}
+
+	if err := idx.prune(validPaths); err != nil {
+		return err
+	}
+	if idx.Embedder != nil {
+		return idx.db.PruneEmbeddings()
+	}
+	return nil
+}
+
+// flushChanges hands the batch collected by process/remove to OnChange and
+// wakes the embedder (every time, so a pass that failed is retried).
+func (idx *Indexer) flushChanges() {
+	batch := idx.changed
+	idx.changed = nil
+	if idx.OnChange != nil && len(batch) > 0 {
+		idx.OnChange(batch)
+	}
+	if idx.Embedder != nil {
+		idx.Embedder.Notify()
+	}
+}
+
+// SyncPaths reindexes only the given vault-relative paths through the same
+// worker/dbUpdate pipeline as Sync. Paths that no longer exist are removed.
+func (idx *Indexer) SyncPaths(rootDir string, relPaths []string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	jobs := make(chan scanJob, len(relPaths))
+	var gone []string
+	for _, rel := range relPaths {
+		rel = filepath.Clean(rel)
+		if !isNoteFile(filepath.Base(rel)) {
+			continue
+		}
+		full := filepath.Join(rootDir, rel)
+		if info, err := os.Stat(full); err != nil || info.IsDir() {
+			gone = append(gone, rel)
+			continue
+		}
+		jobs <- scanJob{FullPath: full, RelPath: rel}
+	}
+	close(jobs)
+
+	defer idx.flushChanges()
+	if _, err := idx.process(rootDir, jobs, false); err != nil {
+		return err
+	}
+	return idx.remove(gone)
+}
+
+// process fans jobs out to the worker pool and applies results in a single transaction.
+// Returns the set of paths that exist on disk (including unchanged and
+// unparsable ones: a broken note keeps its last indexed rows and review
+// history until it is fixed or deleted).
+// full means jobs cover the whole vault, so previous parse errors are all stale.
+// A failed index write aborts the whole batch, except a duplicate ID, which
+// is recorded for that path like a parse error.
+func (idx *Indexer) process(rootDir string, jobs chan scanJob, full bool) (map[string]bool, error) {
+	results := make(chan scanResult, 100)
+
+	validPaths := make(map[string]bool)
+	fresh := make(map[string]bool)
+	var wg sync.WaitGroup
+
+	// 1. Worker Pool
+	numWorkers := idx.Workers
+	if numWorkers < 1 {
+		numWorkers = DefaultWorkers
+	}
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			idx.worker(jobs, results)
+		}()
+	}
+
+	// 2. Closer Goroutine
 	go func() {
 		wg.Wait()
 		close(results)
 	}()
 
-	// 4. Consumer (Main Thread - DB Writer)
+	// 3. Consumer (Main Thread - DB Writer)
 	// SQLite single-writer preference.
 	tx, err := idx.db.Begin()
 	if err != nil {
-		return err
+		for range results {
+		} // Drain so workers exit
+		return nil, err
 	}
 	defer tx.Rollback()
 
-	// Cache current hashes to minimize Read queries inside loop?
-	// Or just query map? For large DB, map is better.
-	// But let's stick to simple logic first. Query inside consumer.
-	// Wait, consumer is single thread, so Query is fine.
+	if full {
+		tx.Exec("DELETE FROM parse_errors")
+	}
 
 	for res := range results {
+		tx.Exec("DELETE FROM parse_errors WHERE path = ?", res.RelPath)
 		if res.Err != nil {
-			fmt.Printf("⚠️ Error processing %s: %v\n", res.RelPath, res.Err)
+			if !errors.Is(res.Err, fs.ErrNotExist) {
+				validPaths[res.RelPath] = true // Still on disk, just invalid
+			}
+			var report *markdown.ValidationReport
+			if errors.As(res.Err, &report) {
+				idx.logf("⚠️ Invalid %s:\n", res.RelPath)
+				for _, v := range report.Violations {
+					idx.logf("    %s:%s %s %s: %s\n", res.RelPath, v.Pos(), v.Severity, v.Code, v.Message)
+				}
+			} else {
+				idx.logf("⚠️ Error processing %s: %v\n", res.RelPath, res.Err)
+			}
+			tx.Exec("INSERT INTO parse_errors (path, error) VALUES (?, ?)", res.RelPath, res.Err.Error())
+			idx.changed = append(idx.changed, res.RelPath)
 			continue
 		}
 
 		validPaths[res.RelPath] = true
 
-		// DB Check logic moved to Consumer or Worker?
 		// Worker computed Hash. Consumer checks DB.
-
 		var currentHash string
 		err := idx.db.QueryRow("SELECT hash FROM nodes WHERE path = ?", res.RelPath).Scan(&currentHash)
 
@@ -122,28 +259,39 @@ func (idx *Indexer) Sync(rootDir string) error {
 		isChanged := err == nil && currentHash != res.Hash
 
 		if isNew {
-			fmt.Printf("[+] New: %s\n", res.RelPath)
+			idx.logf("[+] New: %s\n", res.RelPath)
 		} else if isChanged {
-			fmt.Printf("[*] Changed: %s\n", res.RelPath)
+			idx.logf("[*] Changed: %s\n", res.RelPath)
 		}
 
 		if isNew || isChanged {
+			idx.changed = append(idx.changed, res.RelPath)
 			// Do Indexing
 			if res.Note == nil {
 				// Failed parsing but got hash? Or skip?
 				continue
 			}
-			if err := idx.dbUpdate(tx, res.RelPath, res.Hash, res.Note); err != nil {
-				fmt.Printf("❌ DB Error %s: %v\n", res.RelPath, err)
+			err := idx.dbUpdate(tx, rootDir, res.RelPath, res.Hash, res.Note, fresh)
+			var dup *DuplicateIDError
+			switch {
+			case err == nil:
+				fresh[res.RelPath] = isNew
+			case errors.As(err, &dup):
+				// Nothing was written: the owner's rows stay intact
+				idx.logf("⚠️ %s: %v\n", res.RelPath, dup)
+				tx.Exec("INSERT INTO parse_errors (path, error, duplicate_of) VALUES (?, ?, ?)", res.RelPath, dup.Error(), dup.Owner)
+			default:
+				for range results {
+				} // Drain so workers exit
+				return nil, fmt.Errorf("index %s: %w", res.RelPath, err)
 			}
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return err
+		return nil, err
 	}
-
-	return idx.prune(validPaths)
+	return validPaths, nil
 }
 
 func (idx *Indexer) worker(jobs <-chan scanJob, results chan<- scanResult) {
@@ -193,15 +341,53 @@ func (idx *Indexer) worker(jobs <-chan scanJob, results chan<- scanResult) {
 	}
 }
 
-// dbUpdate extracts DB logic from old indexFile
-func (idx *Indexer) dbUpdate(tx *sql.Tx, relPath, hash string, note *markdown.Note) error {
+// dbUpdate extracts DB logic from old indexFile.
+// fresh holds the paths first indexed by the current batch.
+func (idx *Indexer) dbUpdate(tx *sql.Tx, rootDir, relPath, hash string, note *markdown.Note, fresh map[string]bool) error {
 	id := strings.TrimSuffix(filepath.Base(relPath), filepath.Ext(relPath))
 	title := note.Title
 	if title == "" {
 		title = id
 	}
 
-	_, err := tx.Exec("DELETE FROM nodes WHERE path = ?", relPath)
+	// The ID is the basename, so another path may already own it
+	var owner string
+	err := tx.QueryRow("SELECT path FROM nodes WHERE id = ?", id).Scan(&owner)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return err
+	case owner != relPath:
+		_, statErr := os.Stat(filepath.Join(rootDir, owner))
+		switch {
+		case errors.Is(statErr, fs.ErrNotExist):
+			// The note moved here: re-path its row in place so the review cards
+			// (keyed by ID) survive; remove() of the old path then finds nothing.
+			idx.logf("[>] Moved: %s -> %s\n", owner, relPath)
+		case fresh[owner] && relPath < owner:
+			// Both are new in this batch: the smaller path wins, so a rebuild
+			// does not depend on worker order
+			dup := &DuplicateIDError{ID: id, Owner: relPath}
+			idx.logf("⚠️ %s: %v\n", owner, dup)
+			_, err := tx.Exec("INSERT OR REPLACE INTO parse_errors (path, error, duplicate_of) VALUES (?, ?, ?)", owner, dup.Error(), relPath)
+			if err != nil {
+				return err
+			}
+		default:
+			return &DuplicateIDError{ID: id, Owner: owner}
+		}
+	}
+
+	// After the check above, a row with this ID is this note's own
+	if idx.db.fts {
+		// FTS rows have no foreign key
+		_, err := tx.Exec("DELETE FROM notes_fts WHERE node_id IN (SELECT id FROM nodes WHERE path = ? OR id = ?)", relPath, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("DELETE FROM nodes WHERE path = ? OR id = ?", relPath, id)
 	if err != nil {
 		return err
 	}
@@ -219,6 +405,39 @@ func (idx *Indexer) dbUpdate(tx *sql.Tx, relPath, hash string, note *markdown.No
 		}
 	}
 
+	sections := []struct{ name, content string }{
+		{"Notas", note.Notas},
+		{"Resumen", note.Resumen},
+	}
+	for _, sec := range sections {
+		_, err = tx.Exec("INSERT INTO sections (node_id, name, content) VALUES (?, ?, ?)", id, sec.name, sec.content)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, cue := range note.Cues {
+		_, err = tx.Exec("INSERT INTO cues (node_id, position, text, hash) VALUES (?, ?, ?, ?)", id, i, cue, markdown.CueHash(cue))
+		if err != nil {
+			return err
+		}
+	}
+	reset, err := reconcileCards(tx, id, idx.Now())
+	if err != nil {
+		return err
+	}
+	if reset > 0 {
+		idx.logf("[~] Reset %d review card(s) for %s (cue changed)\n", reset, id)
+	}
+
+	if idx.db.fts {
+		_, err = tx.Exec("INSERT INTO notes_fts (node_id, title, notas, resumen, cues) VALUES (?, ?, ?, ?, ?)",
+			id, title, note.Notas, note.Resumen, strings.Join(note.Cues, "\n"))
+		if err != nil {
+			return err
+		}
+	}
+
 	for _, targetName := range note.Links {
 		_, err = tx.Exec("INSERT OR IGNORE INTO edges (source_id, target_id, type) VALUES (?, ?, ?)", id, targetName, "wiki_link")
 		if err != nil {
@@ -228,9 +447,26 @@ func (idx *Indexer) dbUpdate(tx *sql.Tx, relPath, hash string, note *markdown.No
 	return nil
 }
 
+// reconcileCards keeps review_cards in step with the note's current cues.
+// New cues become cards due at now; cards whose cue text no longer exists are
+// dropped, so a reworded cue restarts its history instead of leaving an orphan.
+// Returns how many cards were reset.
+func reconcileCards(tx *sql.Tx, id string, now time.Time) (int64, error) {
+	_, err := tx.Exec(`INSERT OR IGNORE INTO review_cards (node_id, cue_hash, due)
+		SELECT node_id, hash, ? FROM cues WHERE node_id = ?`, now.Unix(), id)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(`DELETE FROM review_cards
+		WHERE node_id = ? AND cue_hash NOT IN (SELECT hash FROM cues WHERE node_id = ?)`, id, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 func (idx *Indexer) prune(validPaths map[string]bool) error {
-	// ... (Same logic, simple delete)
-	// Re-implement brevity
 	rows, err := idx.db.Query("SELECT path FROM nodes")
 	if err != nil {
 		return err
@@ -245,20 +481,41 @@ func (idx *Indexer) prune(validPaths map[string]bool) error {
 			toDelete = append(toDelete, p)
 		}
 	}
+	rows.Close()
 
-	if len(toDelete) > 0 {
-		fmt.Printf("[-] Pruning %d stale files\n", len(toDelete))
-		tx, err := idx.db.Begin()
-		if err != nil {
-			return err
-		}
-		defer tx.Rollback()
-		for _, p := range toDelete {
-			tx.Exec("DELETE FROM nodes WHERE path = ?", p)
+	return idx.remove(toDelete)
+}
+
+// remove deletes index rows for paths that no longer exist.
+func (idx *Indexer) remove(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	idx.logf("[-] Pruning %d stale files\n", len(paths))
+	idx.changed = append(idx.changed, paths...)
+	tx, err := idx.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, p := range paths {
+		tx.Exec("DELETE FROM parse_errors WHERE path = ?", p)
+		tx.Exec("DELETE FROM review_cards WHERE node_id IN (SELECT id FROM nodes WHERE path = ?)", p)
+		if idx.db.fts {
+			tx.Exec("DELETE FROM notes_fts WHERE node_id IN (SELECT id FROM nodes WHERE path = ?)", p)
 		}
-		return tx.Commit()
+		tx.Exec("DELETE FROM nodes WHERE path = ?", p)
 	}
-	return nil
+	return tx.Commit()
+}
+
+func isNoteFile(name string) bool {
+	return !strings.HasPrefix(name, ".") && strings.HasSuffix(strings.ToLower(name), ".md")
+}
+
+// HashContent is the nodes.hash of a file with this content.
+func HashContent(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))
 }
 
 func calculateHash(path string) (string, error) {
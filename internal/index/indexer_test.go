@@ -0,0 +1,133 @@
+package index
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSyncPersistsSections(t *testing.T) {
+	db := newTestDB(t)
+	vault := t.TempDir()
+
+	os.WriteFile(filepath.Join(vault, "cornell.md"), []byte(`# Cornell
+Fecha: 2024-02-02
+Tipo: estudio
+
+## Notas
+First paragraph.
+
+Second paragraph.
+
+## Cues
+- What is first?
+- What is second?
+
+## Resumen
+Two paragraphs.
+
+## Enlaces
+- [[other]]
+`), 0644)
+
+	if err := NewIndexer(db).Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+
+	notas, err := db.Section("cornell", "Notas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notas != "First paragraph.\n\nSecond paragraph." {
+		t.Errorf("Unexpected Notas: %q", notas)
+	}
+
+	resumen, _ := db.Section("cornell", "Resumen")
+	if resumen != "Two paragraphs." {
+		t.Errorf("Unexpected Resumen: %q", resumen)
+	}
+
+	cues, err := db.Cues("cornell")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"What is first?", "What is second?"}; !reflect.DeepEqual(cues, want) {
+		t.Errorf("Unexpected cues: %v", cues)
+	}
+
+	// Deleting the file cascades to the derived rows
+	os.Remove(filepath.Join(vault, "cornell.md"))
+	if err := NewIndexer(db).Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM cues").Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected cues pruned, got %d", count)
+	}
+}
+
+func TestSyncReportsChanges(t *testing.T) {
+	db := newTestDB(t)
+	vault := t.TempDir()
+	idx := NewIndexer(db)
+	idx.Out = io.Discard
+	var got [][]string
+	idx.OnChange = func(paths []string) { got = append(got, paths) }
+
+	os.WriteFile(filepath.Join(vault, "a.md"), []byte("# A\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\n\n## Cues\n\n## Resumen\n\n## Enlaces\n"), 0644)
+	idx.Sync(vault)
+	idx.Sync(vault) // Nothing changed: no callback
+	os.Remove(filepath.Join(vault, "a.md"))
+	idx.Sync(vault)
+
+	if want := [][]string{{"a.md"}, {"a.md"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected batches: %v", got)
+	}
+}
+
+func TestSyncRecordsDuplicateID(t *testing.T) {
+	db := newTestDB(t)
+	vault := t.TempDir()
+	idx := NewIndexer(db)
+	idx.Out = io.Discard
+
+	os.WriteFile(filepath.Join(vault, "a.md"), []byte("# A\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\nzebra\n\n## Cues\n- Why?\n\n## Resumen\n\n## Enlaces\n"), 0644)
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+	os.Mkdir(filepath.Join(vault, "libro"), 0755)
+	os.WriteFile(filepath.Join(vault, "libro", "a.md"), []byte("# Other A\nFecha: 2024-02-02\nTipo: libro\n\n## Notas\n\n## Cues\n\n## Resumen\n\n## Enlaces\n"), 0644)
+	if err := idx.SyncPaths(vault, []string{filepath.Join("libro", "a.md")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if p, _ := db.LookupPath("a"); p != "a.md" {
+		t.Errorf("Expected the first note to keep its ID, got %q", p)
+	}
+	if notas, _ := db.Section("a", "Notas"); notas != "zebra" {
+		t.Errorf("Expected the first note's sections intact, got %q", notas)
+	}
+	var cards int
+	db.QueryRow("SELECT COUNT(*) FROM review_cards WHERE node_id = 'a'").Scan(&cards)
+	if cards != 1 {
+		t.Errorf("Expected the first note's review card intact, got %d", cards)
+	}
+
+	var msg string
+	db.QueryRow("SELECT error FROM parse_errors WHERE path = ?", filepath.Join("libro", "a.md")).Scan(&msg)
+	if !strings.Contains(msg, "duplicate note ID") {
+		t.Errorf("Expected the collision recorded as a parse error, got %q", msg)
+	}
+
+	// A full Sync reaches the same state instead of failing
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := db.LookupPath("a"); p != "a.md" {
+		t.Errorf("Expected the first note to keep its ID after Sync, got %q", p)
+	}
+}
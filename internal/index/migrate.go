@@ -0,0 +1,99 @@
+package index
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema changes ship as numbered SQL files embedded in the binary:
+// migrations/NNN_name.sql. PRAGMA user_version records the last one applied.
+// Never edit a released migration; add the next number instead.
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// ErrSchemaTooNew means the database was migrated by a newer binary.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Migrations returns the embedded migrations in order. Versions must run 1..n without gaps.
+func Migrations() ([]Migration, error) {
+	entries, err := migrationFS.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+	var migs []Migration
+	for _, e := range entries {
+		num, name, ok := strings.Cut(strings.TrimSuffix(e.Name(), ".sql"), "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s: want NNN_name.sql", e.Name())
+		}
+		content, err := migrationFS.ReadFile(path.Join("migrations", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migs = append(migs, Migration{Version: version, Name: name, SQL: string(content)})
+	}
+	sort.Slice(migs, func(i, j int) bool { return migs[i].Version < migs[j].Version })
+	for i, m := range migs {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %03d_%s: expected version %d", m.Version, m.Name, i+1)
+		}
+	}
+	return migs, nil
+}
+
+// SchemaVersion is the number of the last migration applied (0 = none).
+func (d *DB) SchemaVersion() (int, error) {
+	var v int
+	err := d.QueryRow("PRAGMA user_version").Scan(&v)
+	return v, err
+}
+
+// Migrate applies pending migrations, each in its own transaction together
+// with its user_version bump, then sets up FTS when the driver supports it
+// (optional, so it lives outside the numbered migrations).
+func (d *DB) Migrate() error {
+	migs, err := Migrations()
+	if err != nil {
+		return err
+	}
+	current, err := d.SchemaVersion()
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if current > len(migs) {
+		return fmt.Errorf("%w: database is at v%d, binary knows up to v%d", ErrSchemaTooNew, current, len(migs))
+	}
+
+	for _, m := range migs[current:] {
+		tx, err := d.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(m.SQL); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %03d_%s failed: %w", m.Version, m.Name, err)
+		}
+		// PRAGMA does not take bind parameters
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", m.Version)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %03d_%s failed: %w", m.Version, m.Name, err)
+		}
+	}
+	return d.initFTS()
+}
@@ -0,0 +1,89 @@
+package index
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	migs, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := newTestDB(t)
+	if v, _ := db.SchemaVersion(); v != len(migs) {
+		t.Fatalf("Expected v%d after Migrate, got v%d", len(migs), v)
+	}
+	// Second startup: nothing pending, no error
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Re-running Migrate failed: %v", err)
+	}
+
+	db.Exec("PRAGMA user_version = 999")
+	if err := db.Migrate(); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+func TestMigrateAdoptsLegacyDB(t *testing.T) {
+	// Databases created before migrations existed: full schema, user_version 0
+	db, err := NewDB(filepath.Join(t.TempDir(), "legacy.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	migs, _ := Migrations()
+	if _, err := db.Exec(migs[0].SQL); err != nil {
+		t.Fatal(err)
+	}
+	db.Exec("INSERT INTO review_cards (node_id, cue_hash, due) VALUES ('a', 'h', 0)")
+
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Legacy DB should migrate: %v", err)
+	}
+	var n int
+	db.QueryRow("SELECT COUNT(*) FROM review_cards").Scan(&n)
+	if n != 1 {
+		t.Error("Migration lost existing data")
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	db := newTestDB(t)
+	vault := t.TempDir()
+	os.WriteFile(filepath.Join(vault, "a.md"), []byte("# A\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\n\n## Cues\n- Why?\n\n## Resumen\n\n## Enlaces\n- [[b]]\n"), 0644)
+
+	idx := NewIndexer(db)
+	idx.Out = io.Discard
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+	var cardID int64
+	db.QueryRow("SELECT id FROM review_cards WHERE node_id = 'a'").Scan(&cardID)
+
+	// Corrupt the derived data: a ghost note and a wrong hash
+	db.Exec("INSERT INTO nodes (id, path, hash, last_mod) VALUES ('ghost', 'ghost.md', 'x', 0)")
+	db.Exec("UPDATE nodes SET hash = 'stale', title = 'Wrong' WHERE id = 'a'")
+
+	if err := idx.Rebuild(vault); err != nil {
+		t.Fatal(err)
+	}
+	var nodes, edges int
+	var title string
+	db.QueryRow("SELECT COUNT(*) FROM nodes").Scan(&nodes)
+	db.QueryRow("SELECT COUNT(*) FROM edges").Scan(&edges)
+	db.QueryRow("SELECT title FROM nodes WHERE id = 'a'").Scan(&title)
+	if nodes != 1 || edges != 1 || title != "A" {
+		t.Errorf("Rebuild left nodes=%d edges=%d title=%q", nodes, edges, title)
+	}
+
+	// Review state is not derived from the vault and must survive
+	var after int64
+	db.QueryRow("SELECT id FROM review_cards WHERE node_id = 'a'").Scan(&after)
+	if cardID == 0 || after != cardID {
+		t.Errorf("Review card reset by rebuild: %d → %d", cardID, after)
+	}
+}
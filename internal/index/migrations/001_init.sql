@@ -0,0 +1,147 @@
+-- Migración 001: esquema inicial.
+-- Todo es IF NOT EXISTS para adoptar bases creadas antes de las migraciones (user_version = 0).
+-- Protocolo: SQLite solo guarda metadatos (Fuente de verdad = Markdown)
+
+CREATE TABLE IF NOT EXISTS nodes (
+    id TEXT PRIMARY KEY,           -- Normalmente el path relativo o un ID extraído del Frontmatter
+    path TEXT NOT NULL UNIQUE,     -- Path relativo al root del vault
+    hash TEXT NOT NULL,            -- Checksum del contenido para detectar cambios
+    last_mod INTEGER NOT NULL,     -- Timestamp de modificación del archivo fs
+    title TEXT,                    -- Cached title for fast search
+    indexed_at INTEGER DEFAULT (strftime('%s', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS edges (
+    source_id TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    type TEXT DEFAULT 'wiki_link', -- 'wiki_link', 'parent', 'related'
+    PRIMARY KEY (source_id, target_id, type),
+    FOREIGN KEY(source_id) REFERENCES nodes(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+    node_id TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (node_id, tag),
+    FOREIGN KEY(node_id) REFERENCES nodes(id) ON DELETE CASCADE
+);
+
+-- Cache derivado del contenido Cornell (se reconstruye desde el Markdown en cada Sync)
+CREATE TABLE IF NOT EXISTS cues (
+    node_id TEXT NOT NULL,
+    position INTEGER NOT NULL,     -- Orden dentro de '## Cues' (0-based)
+    text TEXT NOT NULL,
+    hash TEXT NOT NULL,            -- markdown.CueHash(text), clave de review_cards
+    PRIMARY KEY (node_id, position),
+    FOREIGN KEY(node_id) REFERENCES nodes(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS sections (
+    node_id TEXT NOT NULL,
+    name TEXT NOT NULL,            -- 'Notas', 'Resumen'
+    content TEXT NOT NULL,
+    PRIMARY KEY (node_id, name),
+    FOREIGN KEY(node_id) REFERENCES nodes(id) ON DELETE CASCADE
+);
+
+-- Archivos que fallaron ParseFile en el último Sync (para /note doctor)
+CREATE TABLE IF NOT EXISTS parse_errors (
+    path TEXT PRIMARY KEY,         -- Path relativo al root del vault
+    error TEXT NOT NULL,
+    seen_at INTEGER DEFAULT (strftime('%s', 'now'))
+);
+
+-- Estado SRS (NO derivado: sobrevive a re-indexados, por eso sin FOREIGN KEY a nodes).
+-- Clave nota + hash del cue: si el texto cambia, la tarjeta se reinicia.
+CREATE TABLE IF NOT EXISTS review_cards (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    node_id TEXT NOT NULL,
+    cue_hash TEXT NOT NULL,
+    ease REAL NOT NULL DEFAULT 2.5,
+    interval_days INTEGER NOT NULL DEFAULT 0,
+    reps INTEGER NOT NULL DEFAULT 0,
+    lapses INTEGER NOT NULL DEFAULT 0,
+    due INTEGER NOT NULL,          -- Unix seconds
+    last_review INTEGER,
+    UNIQUE (node_id, cue_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_due ON review_cards(due);
+
+-- Scheduler (estado operativo, no derivado del vault)
+CREATE TABLE IF NOT EXISTS jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,            -- Handler: 'message', 'work_ping', ...
+    spec TEXT NOT NULL,            -- '@at ...', '@every 30m' o cron de 5 campos
+    payload TEXT NOT NULL DEFAULT '',
+    next_run INTEGER NOT NULL,     -- Unix seconds
+    created_at INTEGER DEFAULT (strftime('%s', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_next_run ON jobs(next_run);
+
+CREATE TABLE IF NOT EXISTS chat_settings (
+    chat_id INTEGER PRIMARY KEY,
+    timezone TEXT NOT NULL         -- IANA, ej. 'America/Santiago'
+);
+
+-- Work mode (/work): sesiones de foco ligadas a una nota 'tarea'
+CREATE TABLE IF NOT EXISTS work_sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id INTEGER NOT NULL,
+    note_id TEXT NOT NULL,
+    started_at INTEGER NOT NULL,   -- Unix seconds
+    ended_at INTEGER,              -- NULL = sesión activa
+    job_id INTEGER,                -- Job 'work_ping' del scheduler
+    pings INTEGER NOT NULL DEFAULT 0,
+    outcome TEXT                   -- 'off', 'switched', 'done'
+);
+
+-- Daily log (/daily): buffer efímero fuera del vault; se purga al final del día
+CREATE TABLE IF NOT EXISTS daily_entries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id INTEGER NOT NULL,
+    day TEXT NOT NULL,             -- YYYY-MM-DD en la zona horaria del chat
+    created_at INTEGER NOT NULL,   -- Unix seconds
+    text TEXT NOT NULL,
+    promoted_note TEXT             -- Path de la nota 'idea' creada, NULL = efímera
+);
+
+CREATE INDEX IF NOT EXISTS idx_daily_chat_day ON daily_entries(chat_id, day);
+
+-- Bitácora de ediciones hechas por el bot (/undo, /history). Guarda ambas versiones completas (notas ≤ 4000 chars)
+CREATE TABLE IF NOT EXISTS note_ops (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id INTEGER NOT NULL,
+    note_id TEXT NOT NULL,
+    path TEXT NOT NULL,            -- Path relativo al root del vault
+    op TEXT NOT NULL,              -- 'create', 'link', 'cue', 'worklog', ...
+    before_hash TEXT NOT NULL,     -- '' = la nota no existía
+    after_hash TEXT NOT NULL,
+    before TEXT NOT NULL,
+    after TEXT NOT NULL,
+    diff TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    undone_at INTEGER              -- NULL = vigente
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_ops_chat ON note_ops(chat_id, id);
+CREATE INDEX IF NOT EXISTS idx_note_ops_note ON note_ops(note_id, id);
+
+-- Bitácora de auditoría del bot: intentos rechazados por la allowlist
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    chat_id INTEGER NOT NULL,
+    username TEXT NOT NULL,        -- '' si Telegram no lo envía
+    event TEXT NOT NULL,           -- 'denied'
+    action TEXT NOT NULL,          -- Endpoint pedido: '/note create', 'review' (botón), ...
+    role TEXT NOT NULL,            -- '' = desconocido (fuera de la allowlist)
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_user ON audit_log(user_id, id);
+
+CREATE INDEX IF NOT EXISTS idx_nodes_title ON nodes(title);
+CREATE INDEX IF NOT EXISTS idx_edges_target ON edges(target_id);
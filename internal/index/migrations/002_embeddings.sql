@@ -0,0 +1,12 @@
+-- Migración 002: embeddings de notas para /similar y /find~.
+-- Caché derivada, clave = hash del contenido: una nota sin cambios nunca se vuelve a embeber,
+-- y sobrevive a 'zettel rebuild'. Sin FOREIGN KEY a nodes: el Sync completo poda los hashes huérfanos.
+
+CREATE TABLE IF NOT EXISTS embeddings (
+    hash TEXT NOT NULL,            -- nodes.hash del contenido embebido
+    model TEXT NOT NULL,           -- Backend/modelo que lo generó: vectores de modelos distintos no se comparan
+    dims INTEGER NOT NULL,
+    vector BLOB NOT NULL,          -- float32 little-endian
+    created_at INTEGER NOT NULL,   -- Unix seconds
+    PRIMARY KEY (hash, model)
+);
@@ -0,0 +1,13 @@
+-- Migración 003: propuestas del bot que esperan un botón (ej. /ai links).
+-- El callback_data de Telegram admite 64 bytes: el botón lleva solo el id y el contenido vive aquí.
+-- Estado operativo, no derivado del vault: 'zettel rebuild' no lo toca.
+
+CREATE TABLE IF NOT EXISTS pending_actions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,            -- 'link', ...
+    note_id TEXT NOT NULL,         -- Nota que se modificará al aplicar
+    payload TEXT NOT NULL,         -- Según kind: para 'link', el ID destino
+    created_at INTEGER NOT NULL,   -- Unix seconds; caduca tras pendingTTL
+    applied_at INTEGER             -- NULL = pendiente
+);
@@ -0,0 +1,4 @@
+-- Migración 004: colisiones de ID (mismo nombre de archivo en otra carpeta) en parse_errors.
+-- La primera nota conserva el ID; la otra queda fuera del índice hasta que se renombre.
+
+ALTER TABLE parse_errors ADD COLUMN duplicate_of TEXT; -- Path que ya tiene el ID, NULL = fallo de ParseFile
@@ -0,0 +1,170 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Snippet markers. Control characters never appear in notes, so callers can
+// escape the text for their output format and then swap these for highlighting.
+const (
+	HighlightStart = "\x02"
+	HighlightEnd   = "\x03"
+)
+
+var (
+	ErrNoFTS      = errors.New("full-text search unavailable: build with -tags sqlite_fts5")
+	ErrEmptyQuery = errors.New("empty search query")
+)
+
+type SearchHit struct {
+	ID      string
+	Path    string
+	Folder  string // "." for the vault root
+	Title   string
+	Tipo    string
+	Snippet string // Contains HighlightStart/HighlightEnd around matches
+}
+
+// SearchQuery is a parsed /find query.
+type SearchQuery struct {
+	Match string // FTS5 MATCH expression
+	Tipo  string // Optional tipo:<x> filter
+}
+
+// initFTS creates the FTS5 table when the driver supports it.
+// The table is derived data: title + Notas + Resumen + cues per note.
+func (d *DB) initFTS() error {
+	_, err := d.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS notes_fts USING fts5(
+		node_id UNINDEXED, title, notas, resumen, cues,
+		tokenize = 'unicode61 remove_diacritics 2'
+	)`)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			fmt.Fprintln(os.Stderr, "⚠️ SQLite built without FTS5: /find disabled (use -tags sqlite_fts5)")
+			d.fts = false
+			return nil
+		}
+		return fmt.Errorf("failed to create fts table: %w", err)
+	}
+	d.fts = true
+	return nil
+}
+
+func (d *DB) HasFTS() bool {
+	return d.fts
+}
+
+// ParseSearchQuery turns user input into a safe FTS5 expression.
+// Supported: bare terms (AND), "quoted phrases", prefix* and tipo:<x>.
+// Every term is quoted so FTS5 operators/punctuation in user text never cause syntax errors.
+func ParseSearchQuery(raw string) (SearchQuery, error) {
+	var q SearchQuery
+	var parts []string
+
+	for _, tok := range splitQuery(raw) {
+		if !tok.phrase {
+			if v, ok := strings.CutPrefix(strings.ToLower(tok.text), "tipo:"); ok {
+				q.Tipo = v
+				continue
+			}
+		}
+
+		prefix := !tok.phrase && strings.HasSuffix(tok.text, "*")
+		text := strings.TrimRight(tok.text, "*")
+		if strings.TrimFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) == "" {
+			continue
+		}
+
+		term := `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+		parts = append(parts, term)
+	}
+
+	if len(parts) == 0 {
+		return q, ErrEmptyQuery
+	}
+	q.Match = strings.Join(parts, " ")
+	return q, nil
+}
+
+type queryToken struct {
+	text   string
+	phrase bool
+}
+
+func splitQuery(raw string) []queryToken {
+	var tokens []queryToken
+	var cur strings.Builder
+	inQuote := false
+
+	flush := func(phrase bool) {
+		if text := strings.TrimSpace(cur.String()); text != "" {
+			tokens = append(tokens, queryToken{text: text, phrase: phrase})
+		}
+		cur.Reset()
+	}
+
+	for _, r := range raw {
+		switch {
+		case r == '"':
+			flush(inQuote)
+			inQuote = !inQuote
+		case unicode.IsSpace(r) && !inQuote:
+			flush(false)
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush(inQuote)
+	return tokens
+}
+
+// Search runs a ranked full-text query (bm25, title and cues weighted highest).
+func (d *DB) Search(raw string, limit int) ([]SearchHit, error) {
+	if !d.fts {
+		return nil, ErrNoFTS
+	}
+	q, err := ParseSearchQuery(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlQuery := `
+		SELECT n.id, n.path, COALESCE(n.title, ''), COALESCE(t.tag, ''),
+		       snippet(notes_fts, -1, ?, ?, '…', 12)
+		FROM notes_fts f
+		JOIN nodes n ON n.id = f.node_id
+		LEFT JOIN tags t ON t.node_id = n.id
+		WHERE notes_fts MATCH ?`
+	args := []interface{}{HighlightStart, HighlightEnd, q.Match}
+	if q.Tipo != "" {
+		sqlQuery += " AND t.tag = ?"
+		args = append(args, q.Tipo)
+	}
+	sqlQuery += " ORDER BY bm25(notes_fts, 0, 10.0, 1.0, 2.0, 5.0), n.id LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := d.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []SearchHit
+	for rows.Next() {
+		var h SearchHit
+		if err := rows.Scan(&h.ID, &h.Path, &h.Title, &h.Tipo, &h.Snippet); err != nil {
+			return nil, err
+		}
+		h.Folder = filepath.Dir(h.Path)
+		hits = append(hits, h)
+	}
+	return hits, rows.Err()
+}
@@ -0,0 +1,98 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	cases := []struct {
+		raw, match, tipo string
+	}{
+		{`memoria`, `"memoria"`, ""},
+		{`tipo:libro memoria trabajo`, `"memoria" "trabajo"`, "libro"},
+		{`"working memory" recall*`, `"working memory" "recall"*`, ""},
+		{`a-b c:d`, `"a-b" "c:d"`, ""},
+		{`say " spaced phrase "`, `"say" "spaced phrase"`, ""},
+		{`"unterminated phrase`, `"unterminated phrase"`, ""},
+	}
+	for _, tc := range cases {
+		q, err := ParseSearchQuery(tc.raw)
+		if err != nil {
+			t.Errorf("%q: %v", tc.raw, err)
+			continue
+		}
+		if q.Match != tc.match || q.Tipo != tc.tipo {
+			t.Errorf("%q: got (%q, %q), want (%q, %q)", tc.raw, q.Match, q.Tipo, tc.match, tc.tipo)
+		}
+	}
+
+	if _, err := ParseSearchQuery("tipo:libro *"); err != ErrEmptyQuery {
+		t.Errorf("Expected ErrEmptyQuery, got %v", err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	db := newTestDB(t)
+	if !db.HasFTS() {
+		t.Skip("built without sqlite_fts5")
+	}
+	vault := t.TempDir()
+	os.Mkdir(filepath.Join(vault, "libro"), 0755)
+
+	write := func(rel, title, tipo, notas, cue string) {
+		content := "# " + title + "\nFecha: 2024-02-02\nTipo: " + tipo + "\n\n## Notas\n" + notas +
+			"\n\n## Cues\n- " + cue + "\n\n## Resumen\n\n## Enlaces\n"
+		os.WriteFile(filepath.Join(vault, rel), []byte(content), 0644)
+	}
+	write("libro/memoria.md", "Memoria de trabajo", "libro", "La memoria de trabajo es limitada.", "¿Cuántos items retiene?")
+	write("idea-memoria.md", "Idea suelta", "idea", "Algo sobre memoria.", "¿Qué es?")
+	write("otra.md", "Otra", "idea", "Nada relacionado.", "¿Nada?")
+
+	idx := NewIndexer(db)
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+
+	hits, err := db.Search("memoria", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || hits[0].ID != "memoria" {
+		t.Fatalf("Expected title match ranked first, got %+v", hits)
+	}
+	if hits[0].Folder != "libro" || !strings.Contains(hits[0].Snippet, HighlightStart) {
+		t.Errorf("Unexpected hit: %+v", hits[0])
+	}
+
+	hits, _ = db.Search("tipo:idea memoria", 10)
+	if len(hits) != 1 || hits[0].ID != "idea-memoria" {
+		t.Errorf("Tipo filter failed: %+v", hits)
+	}
+
+	hits, _ = db.Search(`"de trabajo"`, 10)
+	if len(hits) != 1 {
+		t.Errorf("Phrase query failed: %+v", hits)
+	}
+
+	hits, _ = db.Search("relac*", 10)
+	if len(hits) != 1 || hits[0].ID != "otra" {
+		t.Errorf("Prefix query failed: %+v", hits)
+	}
+
+	// A second note with the same basename must not clobber the first one's FTS row
+	write("libro/idea-memoria.md", "Copia", "libro", "Otra memoria.", "¿Copia?")
+	idx.SyncPaths(vault, []string{"libro/idea-memoria.md"})
+	if hits, _ = db.Search("tipo:idea memoria", 10); len(hits) != 1 || hits[0].ID != "idea-memoria" {
+		t.Errorf("Duplicate ID clobbered the indexed note: %+v", hits)
+	}
+
+	// Pruned notes leave the FTS table
+	os.Remove(filepath.Join(vault, "otra.md"))
+	idx.Sync(vault)
+	if hits, _ = db.Search("relac*", 10); len(hits) != 0 {
+		t.Errorf("Expected pruned note gone, got %+v", hits)
+	}
+}
@@ -0,0 +1,88 @@
+package index
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Stats is a snapshot of the index for `zettel stats`.
+type Stats struct {
+	SchemaVersion int
+	Notes         int
+	ByTipo        map[string]int
+	Links         int // Distinct source → target pairs
+	Dangling      int // Links whose target is not indexed
+	Cues          int
+	Cards         int // Review cards
+	Due           int // Cards due at the time of the snapshot
+	Embedded      int // Notes whose current content has a vector (any model)
+	ParseErrors   int
+}
+
+func (d *DB) Stats(now time.Time) (*Stats, error) {
+	s := &Stats{ByTipo: map[string]int{}}
+	var err error
+	if s.SchemaVersion, err = d.SchemaVersion(); err != nil {
+		return nil, err
+	}
+
+	counts := []struct {
+		dst   *int
+		query string
+		args  []interface{}
+	}{
+		{&s.Notes, "SELECT COUNT(*) FROM nodes", nil},
+		{&s.Links, "SELECT COUNT(*) FROM (SELECT DISTINCT source_id, target_id FROM edges)", nil},
+		{&s.Dangling, `SELECT COUNT(*) FROM (SELECT DISTINCT source_id, target_id FROM edges e
+			WHERE NOT EXISTS (SELECT 1 FROM nodes n WHERE n.id = e.target_id))`, nil},
+		{&s.Cues, "SELECT COUNT(*) FROM cues", nil},
+		{&s.Cards, "SELECT COUNT(*) FROM review_cards", nil},
+		{&s.Due, "SELECT COUNT(*) FROM review_cards WHERE due <= ?", []interface{}{now.Unix()}},
+		{&s.Embedded, "SELECT COUNT(*) FROM nodes n WHERE EXISTS (SELECT 1 FROM embeddings e WHERE e.hash = n.hash)", nil},
+		{&s.ParseErrors, "SELECT COUNT(*) FROM parse_errors", nil},
+	}
+	for _, c := range counts {
+		if err := d.QueryRow(c.query, c.args...).Scan(c.dst); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := d.Query("SELECT tag, COUNT(*) FROM tags GROUP BY tag")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tipo string
+		var n int
+		if err := rows.Scan(&tipo, &n); err != nil {
+			return nil, err
+		}
+		s.ByTipo[tipo] = n
+	}
+	return s, rows.Err()
+}
+
+func (s *Stats) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("Notes: %d\n", s.Notes))
+
+	tipos := make([]string, 0, len(s.ByTipo))
+	for t := range s.ByTipo {
+		tipos = append(tipos, t)
+	}
+	sort.Strings(tipos)
+	for _, t := range tipos {
+		sb.WriteString(fmt.Sprintf("  %s: %d\n", t, s.ByTipo[t]))
+	}
+
+	sb.WriteString(fmt.Sprintf("Links: %d (%d dangling)\n", s.Links, s.Dangling))
+	sb.WriteString(fmt.Sprintf("Cues: %d\n", s.Cues))
+	sb.WriteString(fmt.Sprintf("Review cards: %d (%d due)\n", s.Cards, s.Due))
+	sb.WriteString(fmt.Sprintf("Embedded: %d of %d\n", s.Embedded, s.Notes))
+	sb.WriteString(fmt.Sprintf("Parse errors: %d\n", s.ParseErrors))
+	sb.WriteString(fmt.Sprintf("Schema: v%d\n", s.SchemaVersion))
+	return sb.String()
+}
@@ -0,0 +1,52 @@
+package index
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatsAndExport(t *testing.T) {
+	db := newTestDB(t)
+	vault := t.TempDir()
+
+	os.WriteFile(filepath.Join(vault, "a.md"), []byte("# Alpha\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\n\n## Cues\n- Why?\n\n## Resumen\n\n## Enlaces\n- [[b]]\n- [[ghost]]\n"), 0644)
+	os.MkdirAll(filepath.Join(vault, "libro"), 0755)
+	os.WriteFile(filepath.Join(vault, "libro", "b.md"), []byte("# Beta\nFecha: 2024-02-02\nTipo: libro\n\n## Notas\n\n## Cues\n\n## Resumen\n\n## Enlaces\n"), 0644)
+	os.WriteFile(filepath.Join(vault, "broken.md"), []byte("no title\n"), 0644)
+
+	idx := NewIndexer(db)
+	idx.Out = io.Discard
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := db.Stats(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Stats{SchemaVersion: s.SchemaVersion, Notes: 2, ByTipo: map[string]int{"idea": 1, "libro": 1},
+		Links: 2, Dangling: 1, Cues: 1, Cards: 1, Due: 1, ParseErrors: 1}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("got %+v, want %+v", s, want)
+	}
+	if out := s.String(); !strings.Contains(out, "Links: 2 (1 dangling)") || !strings.Contains(out, "  libro: 1\n") {
+		t.Errorf("Unexpected report:\n%s", out)
+	}
+
+	notes, err := db.Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantNotes := []ExportedNote{
+		{ID: "a", Path: "a.md", Title: "Alpha", Tipo: "idea", Links: []string{"b", "ghost"}, Cues: []string{"Why?"}},
+		{ID: "b", Path: filepath.Join("libro", "b.md"), Title: "Beta", Tipo: "libro", Links: []string{}, Cues: []string{}},
+	}
+	if !reflect.DeepEqual(notes, wantNotes) {
+		t.Errorf("got %+v, want %+v", notes, wantNotes)
+	}
+}
@@ -0,0 +1,236 @@
+package index
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LinkSuggestion is a note worth linking to, with the signals behind it.
+type LinkSuggestion struct {
+	ID    string
+	Path  string
+	Title string
+	Score float64 // Weighted blend of the signals below, 0-1
+
+	Similarity  float64  // Embedding cosine (0 without embeddings)
+	SharedTerms []string // Rarest terms both notes use, at most 3
+	Backlink    bool     // The candidate already links to the source
+	Via         []string // Notes both are linked with (either direction)
+}
+
+// Signal weights. Embeddings dominate when present; terms and the graph still
+// rank candidates on their own when they are not.
+const (
+	weightSimilarity = 0.5
+	weightTerms      = 0.3
+	weightBacklink   = 0.1
+	weightVia        = 0.1
+)
+
+// Signals renders the evidence in one line, e.g. "similar 82% · shared: memoria, recall".
+func (s LinkSuggestion) Signals() string {
+	var parts []string
+	if s.Similarity > 0 {
+		parts = append(parts, fmt.Sprintf("similar %.0f%%", s.Similarity*100))
+	}
+	if len(s.SharedTerms) > 0 {
+		parts = append(parts, "shared: "+strings.Join(s.SharedTerms, ", "))
+	}
+	if s.Backlink {
+		parts = append(parts, "links here")
+	}
+	if len(s.Via) > 0 {
+		parts = append(parts, "via "+strings.Join(s.Via, ", "))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// SuggestLinks ranks notes that id does not link to yet by embedding
+// similarity (similar, from Embedder.SimilarTo; may be nil), shared terms and
+// the edges around both notes. Returns at most k.
+func (d *DB) SuggestLinks(id string, similar []Match, k int) ([]LinkSuggestion, error) {
+	texts, err := d.noteTerms()
+	if err != nil {
+		return nil, err
+	}
+	source, ok := texts[id]
+	if !ok {
+		return nil, fmt.Errorf("note %s is not indexed", id)
+	}
+
+	neighbours, err := d.neighbours()
+	if err != nil {
+		return nil, err
+	}
+	linked := map[string]bool{id: true}
+	out, err := d.Outlinks(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range out {
+		linked[l.ID] = true
+	}
+
+	// Inverse document frequency, so shared rare words count more than common ones
+	df := map[string]int{}
+	for _, n := range texts {
+		for term := range n.terms {
+			df[term]++
+		}
+	}
+	idf := func(term string) float64 { return math.Log(1 + float64(len(texts))/float64(df[term])) }
+	var sourceWeight float64
+	for term := range source.terms {
+		sourceWeight += idf(term)
+	}
+
+	cos := map[string]float64{}
+	for _, m := range similar {
+		cos[m.ID] = math.Max(m.Score, 0)
+	}
+
+	var suggestions []LinkSuggestion
+	for cid, cand := range texts {
+		if linked[cid] {
+			continue
+		}
+		s := LinkSuggestion{ID: cid, Path: cand.path, Title: cand.title, Similarity: cos[cid]}
+
+		var shared []string
+		var sharedWeight float64
+		for term := range source.terms {
+			if cand.terms[term] {
+				shared = append(shared, term)
+				sharedWeight += idf(term)
+			}
+		}
+		sort.Slice(shared, func(i, j int) bool {
+			if idf(shared[i]) != idf(shared[j]) {
+				return idf(shared[i]) > idf(shared[j])
+			}
+			return shared[i] < shared[j]
+		})
+		if len(shared) > 3 {
+			shared = shared[:3]
+		}
+		s.SharedTerms = shared
+
+		s.Backlink = neighbours[cid][id]
+		for n := range neighbours[id] {
+			if n != cid && neighbours[cid][n] {
+				s.Via = append(s.Via, n)
+			}
+		}
+		sort.Strings(s.Via)
+
+		terms := 0.0
+		if sourceWeight > 0 {
+			terms = sharedWeight / sourceWeight
+		}
+		s.Score = weightSimilarity*s.Similarity + weightTerms*terms + weightVia*math.Min(float64(len(s.Via)), 3)/3
+		if s.Backlink {
+			s.Score += weightBacklink
+		}
+		if s.Score > 0 {
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].ID < suggestions[j].ID
+	})
+	if len(suggestions) > k {
+		suggestions = suggestions[:k]
+	}
+	return suggestions, nil
+}
+
+type noteText struct {
+	path  string
+	title string
+	terms map[string]bool
+}
+
+// noteTerms loads the distinct terms of every indexed note (title, sections, cues).
+func (d *DB) noteTerms() (map[string]noteText, error) {
+	rows, err := d.Query(`SELECT n.id, n.path, COALESCE(n.title, ''),
+			COALESCE((SELECT group_concat(content, char(10)) FROM sections WHERE node_id = n.id), ''),
+			COALESCE((SELECT group_concat(text, char(10)) FROM cues WHERE node_id = n.id), '')
+		FROM nodes n`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	texts := map[string]noteText{}
+	for rows.Next() {
+		var id, path, title, sections, cues string
+		if err := rows.Scan(&id, &path, &title, &sections, &cues); err != nil {
+			return nil, err
+		}
+		texts[id] = noteText{path: path, title: title, terms: terms(title + "\n" + sections + "\n" + cues)}
+	}
+	return texts, rows.Err()
+}
+
+// neighbours maps each indexed note to the notes it is linked with, in either direction.
+func (d *DB) neighbours() (map[string]map[string]bool, error) {
+	rows, err := d.Query(`SELECT DISTINCT source_id, target_id FROM edges WHERE source_id != target_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	graph := map[string]map[string]bool{}
+	add := func(a, b string) {
+		if graph[a] == nil {
+			graph[a] = map[string]bool{}
+		}
+		graph[a][b] = true
+	}
+	for rows.Next() {
+		var src, tgt string
+		if err := rows.Scan(&src, &tgt); err != nil {
+			return nil, err
+		}
+		add(src, tgt)
+		add(tgt, src)
+	}
+	return graph, rows.Err()
+}
+
+// stopwords are frequent Spanish and English words that say nothing about a topic.
+var stopwords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		para como pero más este esta esto estos estas entre sobre desde hasta cuando donde
+		porque también tiene tienen puede pueden hace hacer cada otro otra otros otras todo
+		todos toda todas muy sino según sólo solo aunque ellos ellas nosotros será sido está
+		están eran fueron hay qué cómo cuál
+		that this with from have what when where which their there they them than then
+		about into more most some such only also been were will would could should your
+		does each other these those very just like`) {
+		stopwords[w] = true
+	}
+}
+
+// terms splits text into lowercase words of 4+ letters, minus stopwords.
+func terms(text string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(w) >= 4 && !stopwords[w] {
+			set[w] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,219 @@
+package index
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce groups editor bursts (write + rename + chmod) into one reindex.
+const DefaultDebounce = 500 * time.Millisecond
+
+// Watcher reindexes files as they change on disk (inotify on Linux, kqueue on macOS).
+// Events are debounced and only the affected paths go through Indexer.SyncPaths.
+type Watcher struct {
+	idx      *Indexer
+	root     string
+	debounce time.Duration
+	fsw      *fsnotify.Watcher
+
+	mu      sync.Mutex
+	pending map[string]bool
+	timer   *time.Timer
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewWatcher(idx *Indexer, rootDir string, debounce time.Duration) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+
+	w := &Watcher{
+		idx:      idx,
+		root:     rootDir,
+		debounce: debounce,
+		fsw:      fsw,
+		pending:  make(map[string]bool),
+		done:     make(chan struct{}),
+	}
+
+	// inotify is not recursive: every folder needs its own watch.
+	if err := w.addTree(rootDir); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Start consumes events in the background until Close.
+func (w *Watcher) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.loop()
+	}()
+}
+
+// Close stops watching and waits for a flush already in progress, so no
+// SyncPaths runs after it returns.
+func (w *Watcher) Close() error {
+	close(w.done)
+	err := w.fsw.Close()
+
+	// After this, flush sees done closed before joining the WaitGroup
+	w.mu.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	return err
+}
+
+func (w *Watcher) loop() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			w.handle(ev)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.idx.logf("⚠️ Watcher error: %v\n", err)
+		}
+	}
+}
+
+func (w *Watcher) handle(ev fsnotify.Event) {
+	name := filepath.Base(ev.Name)
+	if strings.HasPrefix(name, ".") {
+		return
+	}
+	rel, err := filepath.Rel(w.root, ev.Name)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case ev.Has(fsnotify.Create):
+		if isDir(ev.Name) {
+			// New or moved-in folder: watch it and index what it already contains.
+			w.addTree(ev.Name)
+			w.enqueueTree(ev.Name)
+			return
+		}
+		if isNoteFile(name) {
+			w.enqueue(rel)
+		}
+
+	case ev.Has(fsnotify.Write):
+		if isNoteFile(name) {
+			w.enqueue(rel)
+		}
+
+	case ev.Has(fsnotify.Remove), ev.Has(fsnotify.Rename):
+		// Rename reports the old name; the new one arrives as Create.
+		if isNoteFile(name) {
+			w.enqueue(rel)
+			return
+		}
+		// Possibly a folder: drop whatever was indexed under it.
+		paths, err := w.idx.db.PathsUnder(rel)
+		if err != nil {
+			return
+		}
+		for _, p := range paths {
+			w.enqueue(p)
+		}
+	}
+}
+
+func (w *Watcher) enqueue(rel string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending[rel] = true
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.debounce, w.flush)
+	} else {
+		w.timer.Reset(w.debounce)
+	}
+}
+
+func (w *Watcher) flush() {
+	w.mu.Lock()
+	select {
+	case <-w.done:
+		w.mu.Unlock()
+		return
+	default:
+	}
+	w.wg.Add(1)
+	defer w.wg.Done()
+	paths := make([]string, 0, len(w.pending))
+	for p := range w.pending {
+		paths = append(paths, p)
+	}
+	w.pending = make(map[string]bool)
+	w.mu.Unlock()
+
+	if len(paths) == 0 {
+		return
+	}
+	if err := w.idx.SyncPaths(w.root, paths); err != nil {
+		w.idx.logf("⚠️ Watch reindex failed: %v\n", err)
+	}
+}
+
+func (w *Watcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != dir {
+			return filepath.SkipDir
+		}
+		if err := w.fsw.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+func (w *Watcher) enqueueTree(dir string) {
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !isNoteFile(d.Name()) {
+			return nil
+		}
+		if rel, err := filepath.Rel(w.root, path); err == nil {
+			w.enqueue(rel)
+		}
+		return nil
+	})
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,132 @@
+package index
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// waitFor polls cond until it holds or the deadline passes.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestWatcherReindexesChanges(t *testing.T) {
+	db := newTestDB(t)
+	vault := t.TempDir()
+	idx := NewIndexer(db)
+
+	w, err := NewWatcher(idx, vault, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Start()
+	defer w.Close()
+
+	indexed := func(id string) bool {
+		_, err := db.LookupPath(id)
+		return err == nil
+	}
+
+	// Create in a new category folder
+	dir := filepath.Join(vault, "libro")
+	os.Mkdir(dir, 0755)
+	note := filepath.Join(dir, "watched.md")
+	os.WriteFile(note, []byte("# Watched\nFecha: 2024-02-02\nTipo: libro\n\n## Notas\n\n## Cues\n\n## Resumen\n\n## Enlaces\n"), 0644)
+	waitFor(t, "create", func() bool { return indexed("watched") })
+
+	// Modify
+	os.WriteFile(note, []byte("# Watched Again\nFecha: 2024-02-02\nTipo: libro\n\n## Notas\n\n## Cues\n\n## Resumen\n\n## Enlaces\n"), 0644)
+	waitFor(t, "modify", func() bool {
+		var title string
+		db.QueryRow("SELECT title FROM nodes WHERE id = 'watched'").Scan(&title)
+		return title == "Watched Again"
+	})
+
+	// Rename
+	renamed := filepath.Join(dir, "renamed.md")
+	os.Rename(note, renamed)
+	waitFor(t, "rename", func() bool { return indexed("renamed") && !indexed("watched") })
+
+	// Delete
+	os.Remove(renamed)
+	waitFor(t, "delete", func() bool { return !indexed("renamed") })
+}
+
+func TestWatcherMoveKeepsReviewCards(t *testing.T) {
+	db := newTestDB(t)
+	vault := t.TempDir()
+	idx := NewIndexer(db)
+	idx.Out = io.Discard
+
+	note := filepath.Join(vault, "a.md")
+	os.WriteFile(note, []byte("# A\nFecha: 2024-02-02\nTipo: libro\n\n## Notas\n\n## Cues\n- Why?\n\n## Resumen\n\n## Enlaces\n"), 0644)
+	os.Mkdir(filepath.Join(vault, "libro"), 0755)
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+	db.Exec("UPDATE review_cards SET interval_days = 9 WHERE node_id = 'a'")
+
+	w, err := NewWatcher(idx, vault, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Start()
+
+	// Same basename, other folder: the old and new path arrive in one batch
+	os.Rename(note, filepath.Join(vault, "libro", "a.md"))
+	waitFor(t, "move", func() bool {
+		p, _ := db.LookupPath("a")
+		return p == filepath.Join("libro", "a.md")
+	})
+	w.Close() // Waits for the removal of the old path too
+
+	var interval int
+	if err := db.QueryRow("SELECT interval_days FROM review_cards WHERE node_id = 'a'").Scan(&interval); err != nil || interval != 9 {
+		t.Errorf("Expected the review card to survive the move, got interval %d (%v)", interval, err)
+	}
+}
+
+func TestWatcherCloseStopsFlush(t *testing.T) {
+	db := newTestDB(t)
+	vault := t.TempDir()
+	os.WriteFile(filepath.Join(vault, "late.md"), []byte("# Late\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\n\n## Cues\n\n## Resumen\n\n## Enlaces\n"), 0644)
+
+	w, err := NewWatcher(NewIndexer(db), vault, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Start()
+	w.enqueue("late.md")
+	w.Close()
+
+	// A timer that fired just before Close must not touch the (closing) DB
+	w.flush()
+	if _, err := db.LookupPath("late"); err == nil {
+		t.Error("flush after Close should be a no-op")
+	}
+}
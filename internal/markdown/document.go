@@ -0,0 +1,180 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Document is the editable form of a note: ParseDocument → edit → Render.
+// Content the model doesn't interpret (stray lines before the first section,
+// unknown sections, spacing inside a section) is kept as raw lines.
+//
+// Canonical layout, which Render always produces:
+//
+//	# Title
+//	Fecha: ...
+//	Tipo: ...
+//	<preamble lines, if any>
+//
+//	## Section
+//	<body>
+//
+//	## Next
+//	...
+type Document struct {
+	Title    string
+	Fecha    string
+	Tipo     string
+	Preamble []string // Non-metadata lines before the first section
+	Sections []*Section
+}
+
+// Section is an H2 block. Lines are raw, with leading/trailing blank lines removed.
+type Section struct {
+	Name  string
+	Lines []string
+}
+
+// NewDocument builds a note with every spec section, in order.
+func NewDocument(title, fecha, tipo, notas string) *Document {
+	d := &Document{Title: title, Fecha: fecha, Tipo: tipo}
+	for _, name := range Sections {
+		d.Sections = append(d.Sections, &Section{Name: name})
+	}
+	if notas != "" {
+		d.Section("Notas").Lines = strings.Split(notas, "\n")
+	}
+	return d
+}
+
+// ParseDocument never fails: validation is Validate's job. Only the first
+// title and the first Fecha/Tipo before any section are interpreted.
+func ParseDocument(content []byte) *Document {
+	d := &Document{}
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+
+	var curr *Section
+	for _, raw := range lines {
+		line := strings.TrimSpace(raw)
+		switch {
+		case strings.HasPrefix(line, "## "):
+			curr = &Section{Name: strings.TrimPrefix(line, "## ")}
+			d.Sections = append(d.Sections, curr)
+		case curr != nil:
+			curr.Lines = append(curr.Lines, raw)
+		case d.Title == "" && strings.HasPrefix(line, "# "):
+			d.Title = strings.TrimPrefix(line, "# ")
+		case d.Fecha == "" && reDate.MatchString(line):
+			d.Fecha = strings.TrimSpace(reDate.FindStringSubmatch(line)[1])
+		case d.Tipo == "" && reType.MatchString(line):
+			d.Tipo = strings.TrimSpace(reType.FindStringSubmatch(line)[1])
+		default:
+			d.Preamble = append(d.Preamble, raw)
+		}
+	}
+
+	d.Preamble = trimBlank(d.Preamble)
+	for _, s := range d.Sections {
+		s.Lines = trimBlank(s.Lines)
+	}
+	return d
+}
+
+// Render serializes the document canonically. For a canonical file,
+// Render(ParseDocument(b)) == b.
+func (d *Document) Render() []byte {
+	sb := strings.Builder{}
+	if d.Title != "" {
+		sb.WriteString("# " + d.Title + "\n")
+	}
+	if d.Fecha != "" {
+		sb.WriteString("Fecha: " + d.Fecha + "\n")
+	}
+	if d.Tipo != "" {
+		sb.WriteString("Tipo: " + d.Tipo + "\n")
+	}
+	for _, line := range d.Preamble {
+		sb.WriteString(line + "\n")
+	}
+	for _, s := range d.Sections {
+		sb.WriteString("\n## " + s.Name + "\n")
+		for _, line := range s.Lines {
+			sb.WriteString(line + "\n")
+		}
+	}
+	return []byte(sb.String())
+}
+
+// Validate checks the rendered document (DefaultMode).
+func (d *Document) Validate(path string) (*Note, error) {
+	return Parse(path, d.Render())
+}
+
+// Section returns the first section with that name, or nil.
+func (d *Document) Section(name string) *Section {
+	for _, s := range d.Sections {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// AddLink appends "- [[target]]" to ## Enlaces. Returns false if the link was already listed.
+func (d *Document) AddLink(target string) (bool, error) {
+	s := d.Section("Enlaces")
+	if s == nil {
+		return false, fmt.Errorf("missing '## Enlaces' section")
+	}
+	item := fmt.Sprintf("- [[%s]]", target)
+	for _, line := range s.Lines {
+		if strings.TrimSpace(line) == item {
+			return false, nil
+		}
+	}
+	s.Lines = append(s.Lines, item)
+	return true, nil
+}
+
+// AddCue appends a cue to ## Cues. A cue is one list item: multi-line input is refused.
+func (d *Document) AddCue(question string) error {
+	s := d.Section("Cues")
+	if s == nil {
+		return fmt.Errorf("missing '## Cues' section")
+	}
+	if strings.ContainsAny(strings.TrimSpace(question), "\r\n") {
+		return fmt.Errorf("cue must be a single line")
+	}
+	s.Lines = append(s.Lines, "- "+strings.TrimSpace(question))
+	return nil
+}
+
+// SetSection replaces the body of a section (e.g. ## Resumen).
+func (d *Document) SetSection(name, body string) error {
+	s := d.Section(name)
+	if s == nil {
+		return fmt.Errorf("missing '## %s' section", name)
+	}
+	s.Lines = trimBlank(strings.Split(strings.TrimSpace(body), "\n"))
+	return nil
+}
+
+// AppendNotas adds a line at the end of ## Notas.
+func (d *Document) AppendNotas(line string) error {
+	s := d.Section("Notas")
+	if s == nil {
+		return fmt.Errorf("missing '## Notas' section")
+	}
+	s.Lines = append(s.Lines, line)
+	return nil
+}
+
+func trimBlank(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,92 @@
+package markdown
+
+import (
+	"fmt"
+	"testing"
+)
+
+const canonical = `# Round Trip
+Fecha: 2024-02-02
+Tipo: idea
+
+## Notas
+First paragraph.
+
+  Indented line kept as is.
+
+## Cues
+- Why?
+
+## Resumen
+
+## Enlaces
+- [[other]]
+`
+
+func TestDocumentRoundTrip(t *testing.T) {
+	if got := string(ParseDocument([]byte(canonical)).Render()); got != canonical {
+		t.Errorf("Canonical file not byte-stable:\n%q\nwant:\n%q", got, canonical)
+	}
+
+	// Non-canonical spacing and CRLF normalize to the canonical form
+	messy := "# Round Trip\r\nFecha: 2024-02-02\r\nTipo: idea\r\n\r\n\r\n## Notas\r\n\r\nFirst paragraph.\r\n\r\n  Indented line kept as is.\r\n\r\n\r\n## Cues\r\n- Why?\r\n## Resumen\r\n\r\n\r\n## Enlaces\r\n- [[other]]"
+	if got := string(ParseDocument([]byte(messy)).Render()); got != canonical {
+		t.Errorf("Messy file not normalized:\n%q", got)
+	}
+
+	// Unknown content survives
+	odd := "# T\nFecha: 2024-02-02\nTipo: idea\nstray line\n\n## Notas\n\n## Extra\nkeep me\n"
+	if got := string(ParseDocument([]byte(odd)).Render()); got != odd {
+		t.Errorf("Unknown content lost:\n%q", got)
+	}
+}
+
+func TestNewDocumentIsValid(t *testing.T) {
+	doc := NewDocument("Fresh", "2024-02-02", "libro", "line 1\nline 2")
+	content := doc.Render()
+	if string(ParseDocument(content).Render()) != string(content) {
+		t.Error("Template is not canonical")
+	}
+	note, err := Parse("fresh.md", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note.Notas != "line 1\nline 2" || note.Type != "libro" {
+		t.Errorf("Unexpected note: %+v", note)
+	}
+}
+
+func TestDocumentEdits(t *testing.T) {
+	doc := ParseDocument([]byte(canonical))
+	if added, err := doc.AddLink("other"); err != nil || added {
+		t.Errorf("Duplicate link should be skipped: %v %v", added, err)
+	}
+	if added, _ := doc.AddLink("new"); !added {
+		t.Error("Expected link added")
+	}
+	doc.AppendNotas("- ⏱ log")
+
+	note, err := doc.Validate("x.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(note.Links) != "[other new]" {
+		t.Errorf("Unexpected links: %v", note.Links)
+	}
+
+	// Edits are only accepted if the result still validates
+	for i := 0; i < MaxCuesCount; i++ {
+		doc.AddCue(fmt.Sprintf("Cue %d?", i))
+	}
+	_, err = doc.Validate("x.md")
+	if report, ok := err.(*ValidationReport); !ok || !report.Has(TooManyCues) {
+		t.Errorf("Expected TooManyCues, got %v", err)
+	}
+
+	if err := doc.AddCue("What?\n- Injected?"); err == nil {
+		t.Error("Expected a multi-line cue refused")
+	}
+	if err := (&Document{Title: "No sections"}).AddCue("Why?"); err == nil {
+		t.Error("Expected missing section error")
+	}
+}
@@ -1,11 +1,14 @@
 package markdown
 
 import (
-	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
@@ -15,6 +18,11 @@ type Note struct {
 	Date  string
 	Type  string
 
+	// Section bodies, trimmed. Blank lines inside a section are kept.
+	Notas   string
+	Resumen string
+	Cues    []string
+
 	Links []string
 }
 
@@ -25,6 +33,45 @@ var (
 	reLinkGlobal = regexp.MustCompile(`\[\[([^\]]+)\]\]`)
 )
 
+// Tipos are the allowed values of "Tipo:" (configurable as vault categories).
+var Tipos = []string{"idea", "estudio", "libro", TaskTipo}
+
+// TaskTipo is the category /work sessions run on; the config requires it among the Tipos.
+const TaskTipo = "tarea"
+
+// Sections are the required H2 sections, in order.
+var Sections = []string{"Notas", "Cues", "Resumen", "Enlaces"}
+
+// Mode selects how structure rules (metadata, sections) are enforced.
+// Size limits are errors in every mode.
+type Mode int
+
+const (
+	Strict  Mode = iota // Every MARKDOWN_SPEC rule is an error
+	Lenient             // Structure rules are warnings, for vaults being migrated
+)
+
+// DefaultMode is used by Parse, ParseFile, Validate and ValidateFile.
+var DefaultMode = Strict
+
+// ParseMode maps "strict" / "lenient" to a Mode.
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "strict", "":
+		return Strict, nil
+	case "lenient":
+		return Lenient, nil
+	}
+	return Strict, fmt.Errorf("unknown parser mode %q (want strict|lenient)", s)
+}
+
+func (m Mode) String() string {
+	if m == Lenient {
+		return "lenient"
+	}
+	return "strict"
+}
+
 const (
 	MaxTotalChars   = 4000
 	MaxTitleChars   = 120
@@ -34,21 +81,137 @@ const (
 	MaxCueLen       = 120
 )
 
+// Limits are the size rules checked by Validate. The spec constants above are
+// the ceiling: a deployment may tighten them (see Check), never loosen them.
+type Limits struct {
+	TotalChars   int
+	TitleChars   int
+	NotasChars   int
+	ResumenChars int
+	CuesCount    int
+	CueLen       int
+}
+
+// SpecLimits are the limits defined by MARKDOWN_SPEC.md.
+var SpecLimits = Limits{
+	TotalChars:   MaxTotalChars,
+	TitleChars:   MaxTitleChars,
+	NotasChars:   MaxNotasChars,
+	ResumenChars: MaxResumenChars,
+	CuesCount:    MaxCuesCount,
+	CueLen:       MaxCueLen,
+}
+
+// DefaultLimits is what Validate enforces; set once at startup.
+var DefaultLimits = SpecLimits
+
+// Check rejects limits that are not positive or exceed the spec.
+func (l Limits) Check() error {
+	for _, f := range []struct {
+		name     string
+		val, max int
+	}{
+		{"total_chars", l.TotalChars, MaxTotalChars},
+		{"title_chars", l.TitleChars, MaxTitleChars},
+		{"notas_chars", l.NotasChars, MaxNotasChars},
+		{"resumen_chars", l.ResumenChars, MaxResumenChars},
+		{"cues_count", l.CuesCount, MaxCuesCount},
+		{"cue_len", l.CueLen, MaxCueLen},
+	} {
+		if f.val < 1 || f.val > f.max {
+			return fmt.Errorf("%s = %d: must be between 1 and the spec limit %d", f.name, f.val, f.max)
+		}
+	}
+	return nil
+}
+
+// CheckResumen applies the ## Resumen rules to a proposed body.
+func (l Limits) CheckResumen(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("empty summary")
+	}
+	if n := utf8.RuneCountInString(strings.TrimSpace(text)); n > l.ResumenChars {
+		return fmt.Errorf("summary has %d chars (max %d)", n, l.ResumenChars)
+	}
+	return nil
+}
+
+// CheckCue applies the per-cue rules (one line, length, trailing '?') to one cue.
+func (l Limits) CheckCue(cue string) error {
+	cue = strings.TrimSpace(cue)
+	if strings.ContainsAny(cue, "\r\n") {
+		return fmt.Errorf("must be a single line")
+	}
+	if n := utf8.RuneCountInString(cue); n > l.CueLen {
+		return fmt.Errorf("%d chars (max %d)", n, l.CueLen)
+	}
+	if !strings.HasSuffix(cue, "?") {
+		return fmt.Errorf("must end with '?'")
+	}
+	return nil
+}
+
+// CueHash identifies a cue by its text (whitespace-normalized).
+// Review history is keyed by it, so rewording a cue deliberately starts a new card.
+func CueHash(cue string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(cue), " ")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParseFile reads and validates a note. On rule violations the error is a *ValidationReport.
 func ParseFile(path string) (*Note, error) {
-	// 1. Read entire file to check global limit efficiently first?
-	// Or stream it. Streaming is better but we need total count.
 	// Given 4000 chars limit, reading to memory is trivial.
 	contentBytes, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	return Parse(path, contentBytes)
+}
+
+// Parse validates in-memory content (e.g. an edit before it is written).
+// path is only recorded in the Note. Warnings are dropped; use Validate to see them.
+func Parse(path string, contentBytes []byte) (*Note, error) {
+	note, report := Validate(path, contentBytes)
+	if report.HasErrors() {
+		return nil, report
+	}
+	return note, nil
+}
 
-	totalLen := utf8.RuneCount(contentBytes)
-	if totalLen > MaxTotalChars {
-		return nil, fmt.Errorf("validation error: total length %d exceeds limit %d", totalLen, MaxTotalChars)
+// ValidateFile is Validate on a file. err is only set for I/O failures.
+func ValidateFile(path string) (*Note, *ValidationReport, error) {
+	contentBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
 	}
+	note, report := Validate(path, contentBytes)
+	return note, report, nil
+}
+
+// Validate parses content and checks every rule in a single pass, in DefaultMode
+// with DefaultLimits.
+// The Note is always returned (best effort) so callers can inspect partial results.
+func Validate(path string, contentBytes []byte) (*Note, *ValidationReport) {
+	return ValidateMode(path, contentBytes, DefaultMode)
+}
 
+// ValidateMode is Validate with an explicit Mode.
+func ValidateMode(path string, contentBytes []byte, mode Mode) (*Note, *ValidationReport) {
+	report := &ValidationReport{Path: path}
 	note := &Note{Path: path}
+	limits := DefaultLimits
+
+	content := string(contentBytes)
+	lines := strings.Split(content, "\n")
+	if n := len(lines); n > 1 && lines[n-1] == "" {
+		lines = lines[:n-1] // Trailing newline
+	}
+
+	if totalLen := utf8.RuneCountInString(content); totalLen > limits.TotalChars {
+		last := len(lines)
+		report.add(Violation{Code: TotalTooLong, Severity: SeverityError, Line: 1, Col: 1, EndLine: last, EndCol: lineEnd(lines[last-1]),
+			Message: fmt.Sprintf("total length %d exceeds limit %d", totalLen, limits.TotalChars)})
+	}
 
 	// Section Buffers
 	var (
@@ -56,35 +219,50 @@ func ParseFile(path string) (*Note, error) {
 		bufNotas    strings.Builder
 		bufResumen  strings.Builder
 		cues        []string
+		cueLines    []int // Source line of each cue
+		metaEnd     int   // Title or last metadata line
+		titleLine   int
+		fechaLine   int
+		tipoLine    int
 	)
+	type span struct{ start, end int } // Header line, last content line
+	sections := map[string]*span{}
+	var headers []span // Every H2 in source order (end unused)
+	var headerNames []string
 
-	scanner := bufio.NewScanner(strings.NewReader(string(contentBytes)))
-	lineNum := 0
-
-	for scanner.Scan() {
-		lineWithSpace := scanner.Text() // Preserve logic? Scan strips newline usually.
-		// utf8.RuneCountInString(line) for checks.
+	for i, lineWithSpace := range lines {
+		lineNum := i + 1
+		lineWithSpace = strings.TrimRight(lineWithSpace, "\r")
 		line := strings.TrimSpace(lineWithSpace)
-		lineNum++
 
 		if line == "" {
+			// Keep paragraph breaks inside free-text sections
+			switch currSection {
+			case "Notas":
+				bufNotas.WriteString("\n")
+			case "Resumen":
+				bufResumen.WriteString("\n")
+			}
 			continue
 		}
 
-		// 1. Title (Must be Line 1 technically, or first non-empty)
+		// 1. Title (Spec: "Línea 1, H1")
 		if note.Title == "" {
 			if strings.HasPrefix(line, "# ") {
 				title := strings.TrimPrefix(line, "# ")
-				if utf8.RuneCountInString(title) > MaxTitleChars {
-					return nil, fmt.Errorf("validation error: title length %d exceeds limit %d", utf8.RuneCountInString(title), MaxTitleChars)
+				if n := utf8.RuneCountInString(title); n > limits.TitleChars {
+					report.add(Violation{Code: TitleTooLong, Severity: SeverityError,
+						Line: lineNum, Col: indentCol(lineWithSpace) + 2, EndLine: lineNum, EndCol: lineEnd(lineWithSpace),
+						Message: fmt.Sprintf("title length %d exceeds limit %d", n, limits.TitleChars)})
 				}
 				note.Title = title
+				metaEnd, titleLine = lineNum, lineNum
 				continue
 			}
-			// Strict: If first line is not title?
-			// Spec says "Línea 1, H1".
 			if lineNum == 1 {
-				return nil, fmt.Errorf("validation error: first line must be H1 Title")
+				report.add(Violation{Code: TitleNotFirstLine, Severity: SeverityError,
+					Line: 1, Col: 1, EndLine: 1, EndCol: lineEnd(lineWithSpace),
+					Message: "first line must be H1 Title"})
 			}
 		}
 
@@ -92,10 +270,12 @@ func ParseFile(path string) (*Note, error) {
 		if currSection == "" {
 			if matches := reDate.FindStringSubmatch(line); len(matches) > 1 {
 				note.Date = strings.TrimSpace(matches[1])
+				metaEnd, fechaLine = lineNum, lineNum
 				continue
 			}
 			if matches := reType.FindStringSubmatch(line); len(matches) > 1 {
 				note.Type = strings.TrimSpace(matches[1])
+				metaEnd, tipoLine = lineNum, lineNum
 				continue
 			}
 		}
@@ -103,8 +283,16 @@ func ParseFile(path string) (*Note, error) {
 		// 3. Section Switching
 		if strings.HasPrefix(line, "## ") {
 			currSection = strings.TrimPrefix(line, "## ")
+			headers = append(headers, span{start: lineNum})
+			headerNames = append(headerNames, currSection)
+			if sections[currSection] == nil {
+				sections[currSection] = &span{start: lineNum, end: lineNum}
+			}
 			continue
 		}
+		if sec := sections[currSection]; sec != nil {
+			sec.end = lineNum
+		}
 
 		// 4. Content Capture & Specific Validation
 		switch currSection {
@@ -116,6 +304,7 @@ func ParseFile(path string) (*Note, error) {
 			if strings.HasPrefix(line, "- ") {
 				cueText := strings.TrimPrefix(line, "- ")
 				cues = append(cues, cueText)
+				cueLines = append(cueLines, lineNum)
 			}
 		case "Enlaces":
 			// Just extractor logic below
@@ -131,30 +320,193 @@ func ParseFile(path string) (*Note, error) {
 		}
 	}
 
+	note.Notas = strings.TrimSpace(bufNotas.String())
+	note.Resumen = strings.TrimSpace(bufResumen.String())
+	note.Cues = cues
+
 	// Post-Scan Validation
-	if utf8.RuneCountInString(bufNotas.String()) > MaxNotasChars {
-		return nil, fmt.Errorf("validation error: 'Notas' section exceeds %d chars", MaxNotasChars)
+	sectionLimits := []struct {
+		name    string
+		content string
+		max     int
+	}{
+		{"Notas", note.Notas, limits.NotasChars},
+		{"Resumen", note.Resumen, limits.ResumenChars},
 	}
-	if utf8.RuneCountInString(bufResumen.String()) > MaxResumenChars {
-		return nil, fmt.Errorf("validation error: 'Resumen' section exceeds %d chars", MaxResumenChars)
+	for _, l := range sectionLimits {
+		if n := utf8.RuneCountInString(l.content); n > l.max {
+			sec := sections[l.name]
+			report.add(Violation{Code: SectionTooLong, Severity: SeverityError,
+				Line: sec.start, Col: 1, EndLine: sec.end, EndCol: lineEnd(lines[sec.end-1]),
+				Message: fmt.Sprintf("'%s' section exceeds %d chars (%d)", l.name, l.max, n)})
+		}
 	}
 
 	// Cues Usage Validation
-	if len(cues) > MaxCuesCount {
-		return nil, fmt.Errorf("validation error: too many cues (%d > %d)", len(cues), MaxCuesCount)
+	if len(cues) > limits.CuesCount {
+		first, last := cueLines[limits.CuesCount], cueLines[len(cueLines)-1]
+		report.add(Violation{Code: TooManyCues, Severity: SeverityError,
+			Line: first, Col: 1, EndLine: last, EndCol: lineEnd(lines[last-1]),
+			Message: fmt.Sprintf("too many cues (%d > %d)", len(cues), limits.CuesCount)})
 	}
 	for i, c := range cues {
-		if utf8.RuneCountInString(c) > MaxCueLen {
-			return nil, fmt.Errorf("validation error: cue %d length exceeds %d", i+1, MaxCueLen)
+		raw := lines[cueLines[i]-1]
+		if n := utf8.RuneCountInString(c); n > limits.CueLen {
+			report.add(Violation{Code: CueTooLong, Severity: SeverityError,
+				Line: cueLines[i], Col: indentCol(raw) + 2, EndLine: cueLines[i], EndCol: lineEnd(raw),
+				Message: fmt.Sprintf("cue %d length %d exceeds %d", i+1, n, limits.CueLen)})
 		}
 		if !strings.HasSuffix(strings.TrimSpace(c), "?") {
-			return nil, fmt.Errorf("validation error: cue '%s' must end with '?'", c)
+			report.add(Violation{Code: CueMissingQuestionMark, Severity: SeverityError,
+				Line: cueLines[i], Col: lineEnd(raw),
+				Message: fmt.Sprintf("cue '%s' must end with '?'", c)})
 		}
 	}
 
-	if note.Title == "" {
-		return nil, fmt.Errorf("missing title")
+	if note.Title == "" && !report.Has(TitleNotFirstLine) {
+		report.add(Violation{Code: MissingTitle, Severity: SeverityError, Line: 1, Col: 1, Message: "missing title"})
 	}
 
-	return note, nil
+	structure := SeverityError
+	if mode == Lenient {
+		structure = SeverityWarning
+	}
+
+	// Metadata belongs right after the title; reported where it is expected
+	metaLine := metaEnd + 1
+	if note.Date == "" {
+		report.add(Violation{Code: MissingFecha, Severity: structure, Line: metaLine, Col: 1, Message: "missing 'Fecha: YYYY-MM-DD'"})
+	} else if _, err := time.Parse("2006-01-02", note.Date); err != nil {
+		raw := lines[fechaLine-1]
+		report.add(Violation{Code: InvalidFecha, Severity: structure,
+			Line: fechaLine, Col: valueCol(raw), EndLine: fechaLine, EndCol: lineEnd(raw),
+			Message: fmt.Sprintf("Fecha '%s' is not YYYY-MM-DD", note.Date)})
+	}
+	if note.Type == "" {
+		report.add(Violation{Code: MissingTipo, Severity: structure, Line: metaLine, Col: 1, Message: "missing 'Tipo: " + strings.Join(Tipos, "|") + "'"})
+	} else if !IsTipo(note.Type) {
+		raw := lines[tipoLine-1]
+		report.add(Violation{Code: InvalidTipo, Severity: structure,
+			Line: tipoLine, Col: valueCol(raw), EndLine: tipoLine, EndCol: lineEnd(raw),
+			Message: fmt.Sprintf("Tipo '%s' is not one of %s", note.Type, strings.Join(Tipos, "|"))})
+	}
+	if titleLine > 0 {
+		if fechaLine > 0 && fechaLine != titleLine+1 {
+			report.add(Violation{Code: MetadataMisplaced, Severity: structure, Line: fechaLine, Col: 1, EndLine: fechaLine, EndCol: lineEnd(lines[fechaLine-1]),
+				Message: "'Fecha' must be on the line right after the title"})
+		}
+		want := titleLine + 1
+		if fechaLine > 0 {
+			want = fechaLine + 1
+		}
+		if tipoLine > 0 && tipoLine != want {
+			report.add(Violation{Code: MetadataMisplaced, Severity: structure, Line: tipoLine, Col: 1, EndLine: tipoLine, EndCol: lineEnd(lines[tipoLine-1]),
+				Message: "'Tipo' must be on the line right after 'Fecha'"})
+		}
+	}
+
+	// Sections: known, once each, in spec order, none missing
+	seen := map[string]bool{}
+	var known []int // Indexes into headers of first occurrences of spec sections
+	for i, h := range headers {
+		name, raw := headerNames[i], lines[h.start-1]
+		switch {
+		case sectionOrder(name) < 0:
+			report.add(Violation{Code: UnknownSection, Severity: structure, Line: h.start, Col: 1, EndLine: h.start, EndCol: lineEnd(raw),
+				Message: fmt.Sprintf("unknown section '## %s' (allowed: %s)", name, strings.Join(Sections, ", "))})
+		case seen[name]:
+			report.add(Violation{Code: DuplicateSection, Severity: structure, Line: h.start, Col: 1, EndLine: h.start, EndCol: lineEnd(raw),
+				Message: fmt.Sprintf("duplicate section '## %s'", name)})
+		default:
+			known = append(known, i)
+		}
+		seen[name] = true
+	}
+	// Blame only the sections outside the longest correctly ordered run
+	inOrder := longestOrdered(known, func(i int) int { return sectionOrder(headerNames[i]) })
+	for _, i := range known {
+		if !inOrder[i] {
+			h := headers[i]
+			report.add(Violation{Code: SectionOutOfOrder, Severity: structure, Line: h.start, Col: 1, EndLine: h.start, EndCol: lineEnd(lines[h.start-1]),
+				Message: fmt.Sprintf("section '## %s' out of order (want %s)", headerNames[i], strings.Join(Sections, ", "))})
+		}
+	}
+	for _, name := range Sections {
+		if !seen[name] {
+			last := len(lines)
+			report.add(Violation{Code: MissingSection, Severity: structure, Line: last, Col: lineEnd(lines[last-1]),
+				Message: fmt.Sprintf("missing section '## %s'", name)})
+		}
+	}
+
+	sort.SliceStable(report.Violations, func(i, j int) bool {
+		a, b := report.Violations[i], report.Violations[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	return note, report
+}
+
+// IsTipo reports whether t is one of the configured Tipos.
+func IsTipo(t string) bool {
+	for _, known := range Tipos {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+func sectionOrder(name string) int {
+	for i, known := range Sections {
+		if name == known {
+			return i
+		}
+	}
+	return -1
+}
+
+// longestOrdered returns the members of the longest subsequence of items whose
+// rank increases. Few sections per note, so O(n²) is fine.
+func longestOrdered(items []int, rank func(int) int) map[int]bool {
+	best := make([]int, len(items)) // Length of the best run ending at i
+	prev := make([]int, len(items))
+	end := -1
+	for i := range items {
+		best[i], prev[i] = 1, -1
+		for j := 0; j < i; j++ {
+			if rank(items[j]) < rank(items[i]) && best[j]+1 > best[i] {
+				best[i], prev[i] = best[j]+1, j
+			}
+		}
+		if end < 0 || best[i] > best[end] {
+			end = i
+		}
+	}
+	members := map[int]bool{}
+	for i := end; i >= 0; i = prev[i] {
+		members[items[i]] = true
+	}
+	return members
+}
+
+// valueCol is the column right after "Key:" and its spacing.
+func valueCol(raw string) int {
+	key, value, _ := strings.Cut(raw, ":")
+	return utf8.RuneCountInString(key) + 1 + utf8.RuneCountInString(value) - utf8.RuneCountInString(strings.TrimLeft(value, " \t")) + 1
+}
+
+// indentCol is the 1-based column of the first non-space rune.
+func indentCol(raw string) int {
+	return utf8.RuneCountInString(raw) - utf8.RuneCountInString(strings.TrimLeft(raw, " \t")) + 1
+}
+
+// lineEnd is the 1-based column of the last non-space rune (1 for blank lines).
+func lineEnd(raw string) int {
+	if n := utf8.RuneCountInString(strings.TrimRight(raw, " \t\r")); n > 0 {
+		return n
+	}
+	return 1
 }
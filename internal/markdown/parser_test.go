@@ -0,0 +1,121 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidateCollectsAll(t *testing.T) {
+	content := "# " + strings.Repeat("T", MaxTitleChars+1) + `
+Tipo: idea
+
+## Notas
+` + strings.Repeat("n", MaxNotasChars+1) + `
+
+## Cues
+- One?
+- Two
+
+## Resumen
+
+## Enlaces
+`
+	note, report := Validate("x.md", []byte(content))
+	if note.Title == "" || note.Type != "idea" {
+		t.Errorf("Expected best-effort note, got %+v", note)
+	}
+
+	want := []Violation{
+		{Code: TitleTooLong, Severity: SeverityError, Line: 1, Col: 3, EndLine: 1, EndCol: 123},
+		{Code: MissingFecha, Severity: SeverityError, Line: 3, Col: 1, EndLine: 3, EndCol: 1},
+		{Code: SectionTooLong, Severity: SeverityError, Line: 4, Col: 1, EndLine: 5, EndCol: MaxNotasChars + 1},
+		{Code: CueMissingQuestionMark, Severity: SeverityError, Line: 9, Col: 5, EndLine: 9, EndCol: 5},
+	}
+	if len(report.Violations) != len(want) {
+		t.Fatalf("Expected %d violations, got:\n%s", len(want), report)
+	}
+	for i, w := range want {
+		got := report.Violations[i]
+		got.Message = ""
+		if got != w {
+			t.Errorf("Violation %d: got %+v, want %+v", i, got, w)
+		}
+	}
+	if !report.HasErrors() {
+		t.Error("Expected errors")
+	}
+
+	if _, err := Parse("x.md", []byte(content)); err == nil {
+		t.Error("Parse should reject")
+	} else if _, ok := err.(*ValidationReport); !ok {
+		t.Errorf("Expected *ValidationReport, got %T", err)
+	}
+}
+
+func TestStrictStructure(t *testing.T) {
+	content := `# Title
+Tipo: idea
+Fecha: 2024-2-2
+
+## Cues
+- Why?
+
+## Notas
+Body
+
+## Notas
+
+## Resumen
+
+## Extra
+`
+	_, report := ValidateMode("x.md", []byte(content), Strict)
+	var got []Code
+	for _, v := range report.Violations {
+		if v.Severity != SeverityError {
+			t.Errorf("Strict structure rule should be an error: %v", v)
+		}
+		got = append(got, v.Code)
+	}
+	want := []Code{MetadataMisplaced, MetadataMisplaced, InvalidFecha, SectionOutOfOrder, DuplicateSection, UnknownSection, MissingSection}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v\n%s", got, want, report)
+	}
+
+	// Same note in Lenient mode: all warnings, note accepted
+	_, report = ValidateMode("x.md", []byte(content), Lenient)
+	if report.HasErrors() || len(report.Violations) != len(want) {
+		t.Errorf("Lenient should only warn:\n%s", report)
+	}
+}
+
+func TestTitleNotFirstLine(t *testing.T) {
+	_, err := Parse("x.md", []byte("Not a title\n"))
+	report, ok := err.(*ValidationReport)
+	if !ok || !report.Has(TitleNotFirstLine) || report.Has(MissingTitle) {
+		t.Errorf("Expected TitleNotFirstLine without MissingTitle, got %v", err)
+	}
+}
+
+func TestTightenedLimits(t *testing.T) {
+	defer func(l Limits) { DefaultLimits = l }(DefaultLimits)
+
+	content := "# Title\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\n\n## Cues\n- One?\n- Two?\n- Three?\n\n## Resumen\n\n## Enlaces\n"
+	if _, err := Parse("x.md", []byte(content)); err != nil {
+		t.Fatalf("Valid under spec limits: %v", err)
+	}
+
+	DefaultLimits.CuesCount = 2
+	_, err := Parse("x.md", []byte(content))
+	if report, ok := err.(*ValidationReport); !ok || !report.Has(TooManyCues) {
+		t.Errorf("Expected TooManyCues under a tightened limit, got %v", err)
+	}
+
+	if err := (Limits{TotalChars: 5000}).Check(); err == nil {
+		t.Error("Limits above the spec must be rejected")
+	}
+	if err := SpecLimits.Check(); err != nil {
+		t.Errorf("Spec limits must pass: %v", err)
+	}
+}
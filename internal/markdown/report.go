@@ -0,0 +1,124 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Code identifies a rule of MARKDOWN_SPEC.md.
+type Code string
+
+const (
+	TotalTooLong           Code = "TotalTooLong"
+	MissingTitle           Code = "MissingTitle"
+	TitleNotFirstLine      Code = "TitleNotFirstLine"
+	TitleTooLong           Code = "TitleTooLong"
+	MissingFecha           Code = "MissingFecha"
+	MissingTipo            Code = "MissingTipo"
+	SectionTooLong         Code = "SectionTooLong"
+	TooManyCues            Code = "TooManyCues"
+	CueTooLong             Code = "CueTooLong"
+	CueMissingQuestionMark Code = "CueMissingQuestionMark"
+
+	// Structure rules; errors in Strict mode, warnings in Lenient
+	InvalidFecha      Code = "InvalidFecha"
+	InvalidTipo       Code = "InvalidTipo"
+	MetadataMisplaced Code = "MetadataMisplaced"
+	MissingSection    Code = "MissingSection"
+	SectionOutOfOrder Code = "SectionOutOfOrder"
+	DuplicateSection  Code = "DuplicateSection"
+	UnknownSection    Code = "UnknownSection"
+)
+
+type Severity int
+
+const (
+	SeverityWarning Severity = iota // Reported, note still usable
+	SeverityError                   // Note rejected
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Violation is one broken rule. Positions are 1-based; columns count runes.
+// End is inclusive; a zero-width position has End == Start.
+type Violation struct {
+	Code     Code
+	Severity Severity
+	Line     int
+	Col      int
+	EndLine  int
+	EndCol   int
+	Message  string
+}
+
+// Pos renders the range as "3:1" or "3:1-5:12".
+func (v Violation) Pos() string {
+	if v.EndLine == v.Line && v.EndCol == v.Col {
+		return fmt.Sprintf("%d:%d", v.Line, v.Col)
+	}
+	return fmt.Sprintf("%d:%d-%d:%d", v.Line, v.Col, v.EndLine, v.EndCol)
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s %s %s: %s", v.Pos(), v.Severity, v.Code, v.Message)
+}
+
+// ValidationReport collects every violation found in one pass over a note.
+// It is the error returned by Parse/ParseFile when any violation is an error.
+type ValidationReport struct {
+	Path       string
+	Violations []Violation // In source order
+}
+
+func (r *ValidationReport) add(v Violation) {
+	if v.EndLine == 0 {
+		v.EndLine, v.EndCol = v.Line, v.Col
+	}
+	r.Violations = append(r.Violations, v)
+}
+
+// HasErrors reports whether any violation rejects the note.
+func (r *ValidationReport) HasErrors() bool {
+	for _, v := range r.Violations {
+		if v.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Has reports whether a violation with code was found.
+func (r *ValidationReport) Has(code Code) bool {
+	for _, v := range r.Violations {
+		if v.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Error is a single line (logs, parse_errors); errors only.
+func (r *ValidationReport) Error() string {
+	var parts []string
+	for _, v := range r.Violations {
+		if v.Severity == SeverityError {
+			parts = append(parts, fmt.Sprintf("%s %s: %s", v.Pos(), v.Code, v.Message))
+		}
+	}
+	return "validation error: " + strings.Join(parts, "; ")
+}
+
+// String renders one violation per line, warnings included.
+func (r *ValidationReport) String() string {
+	sb := strings.Builder{}
+	for _, v := range r.Violations {
+		sb.WriteString(v.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,78 @@
+// Package neural talks to the language model. Everything above it (bot,
+// indexer) depends only on Backend, so switching provider or model is a
+// config change.
+package neural
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Backend is a text model provider. Implementations must be safe for
+// concurrent use.
+type Backend interface {
+	// Generate returns the full completion for prompt.
+	Generate(prompt string) (string, error)
+	// GenerateJSON is Generate constrained to a JSON document matching schema
+	// (a JSON Schema object). The caller still validates what it decodes.
+	GenerateJSON(prompt string, schema json.RawMessage) (string, error)
+	// Stream calls onChunk with each piece of the completion as it arrives and
+	// returns the full text. An error from onChunk aborts the request.
+	Stream(prompt string, onChunk func(string) error) (string, error)
+	// Embed returns the embedding vector of text.
+	Embed(text string) ([]float32, error)
+}
+
+// Backends lists the values accepted by Options.Backend.
+var Backends = []string{"ollama", "openai", "fake"}
+
+// DefaultTimeout bounds a whole request, streaming included.
+const DefaultTimeout = 2 * time.Minute
+
+// Options selects and configures a backend. Empty fields take the backend's defaults.
+type Options struct {
+	Backend    string // ollama|openai|fake
+	URL        string
+	Model      string
+	EmbedModel string // "" = Model
+	APIKey     string // Sent as a Bearer token (openai only)
+	Timeout    time.Duration
+}
+
+// New builds the backend described by o.
+func New(o Options) (Backend, error) {
+	timeout := o.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	httpClient := &http.Client{Timeout: timeout}
+
+	switch o.Backend {
+	case "ollama", "":
+		c := NewOllama(o.URL, o.Model)
+		c.EmbedModel = o.EmbedModel
+		c.HTTP = httpClient
+		return c, nil
+	case "openai":
+		c := NewOpenAI(o.URL, o.Model, o.APIKey)
+		c.EmbedModel = o.EmbedModel
+		c.HTTP = httpClient
+		return c, nil
+	case "fake":
+		return &Fake{}, nil
+	}
+	return nil, fmt.Errorf("unknown backend %q (want %s)", o.Backend, strings.Join(Backends, "|"))
+}
+
+// httpError reads a short excerpt of a failed response for the error message.
+func httpError(provider string, resp *http.Response) error {
+	buf := make([]byte, 200)
+	n, _ := resp.Body.Read(buf)
+	if msg := strings.TrimSpace(string(buf[:n])); msg != "" {
+		return fmt.Errorf("%s error: %s: %s", provider, resp.Status, msg)
+	}
+	return fmt.Errorf("%s error: %s", provider, resp.Status)
+}
@@ -0,0 +1,153 @@
+package neural
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+)
+
+func TestOllama(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case r.URL.Path == "/api/generate" && req["stream"] == true:
+			fmt.Fprintln(w, `{"response":"Hola ","done":false}`)
+			fmt.Fprintln(w, `{"response":"mundo","done":false}`)
+			fmt.Fprintln(w, `{"response":"","done":true}`)
+		case r.URL.Path == "/api/generate" && req["format"] != nil:
+			fmt.Fprintf(w, `{"response":"{\"type\":\"%s\"}","done":true}`, req["format"].(map[string]interface{})["type"])
+		case r.URL.Path == "/api/generate":
+			fmt.Fprintf(w, `{"response":"echo %s","done":true}`, req["model"])
+		case r.URL.Path == "/api/embeddings" && req["model"] == "nomic":
+			fmt.Fprintln(w, `{"embedding":[0.5,0.25]}`)
+		default:
+			http.Error(w, `{"error":"model not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewOllama(ts.URL, "llama3")
+	if got, err := c.Generate("hi"); err != nil || got != "echo llama3" {
+		t.Errorf("Generate = %q, %v", got, err)
+	}
+
+	if got, err := c.GenerateJSON("hi", summarySchema(markdown.SpecLimits)); err != nil || got != `{"type":"object"}` {
+		t.Errorf("GenerateJSON should send the schema as format: %q, %v", got, err)
+	}
+
+	var chunks []string
+	full, err := c.Stream("hi", func(s string) error { chunks = append(chunks, s); return nil })
+	if err != nil || full != "Hola mundo" || len(chunks) != 2 {
+		t.Errorf("Stream = %q %v, %v", full, chunks, err)
+	}
+
+	if _, err := c.Embed("x"); err == nil || !strings.Contains(err.Error(), "model not found") {
+		t.Errorf("Expected server error to surface, got %v", err)
+	}
+	c.EmbedModel = "nomic"
+	if vec, err := c.Embed("x"); err != nil || len(vec) != 2 || vec[0] != 0.5 {
+		t.Errorf("Embed = %v, %v", vec, err)
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req chatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case r.URL.Path == "/v1/chat/completions" && req.Stream:
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hola \"}}]}\n\n")
+			fmt.Fprint(w, ": keep-alive\n\n")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"mundo\"}}]}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		case r.URL.Path == "/v1/chat/completions" && req.ResponseFormat != nil:
+			fmt.Fprintf(w, `{"choices":[{"message":{"content":"%s %s"}}]}`, req.ResponseFormat.Type, req.ResponseFormat.JSONSchema.Name)
+		case r.URL.Path == "/v1/chat/completions":
+			fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":"%s: %s"}}]}`, req.Model, req.Messages[0].Content)
+		case r.URL.Path == "/v1/embeddings":
+			fmt.Fprint(w, `{"data":[{"embedding":[1,0,0]}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	if _, err := NewOpenAI(ts.URL+"/v1", "m", "").Generate("hi"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected 401 without key, got %v", err)
+	}
+
+	c := NewOpenAI(ts.URL+"/v1/", "qwen", "secret")
+	if got, err := c.Generate("hi"); err != nil || got != "qwen: hi" {
+		t.Errorf("Generate = %q, %v", got, err)
+	}
+	if got, err := c.GenerateJSON("hi", summarySchema(markdown.SpecLimits)); err != nil || got != "json_schema answer" {
+		t.Errorf("GenerateJSON should send response_format: %q, %v", got, err)
+	}
+
+	var chunks []string
+	full, err := c.Stream("hi", func(s string) error { chunks = append(chunks, s); return nil })
+	if err != nil || full != "Hola mundo" || len(chunks) != 2 {
+		t.Errorf("Stream = %q %v, %v", full, chunks, err)
+	}
+
+	if vec, err := c.Embed("x"); err != nil || len(vec) != 3 {
+		t.Errorf("Embed = %v, %v", vec, err)
+	}
+}
+
+func TestFake(t *testing.T) {
+	f := &Fake{}
+	a, _ := f.Generate("same")
+	b, _ := f.Generate("same")
+	c, _ := f.Generate("other")
+	if a != b || a == c {
+		t.Errorf("Fake should be deterministic per prompt: %q %q %q", a, b, c)
+	}
+	if len(f.Prompts()) != 3 {
+		t.Errorf("Prompts not recorded: %v", f.Prompts())
+	}
+
+	cosine := func(x, y []float32) (dot float32) {
+		for i := range x {
+			dot += x[i] * y[i]
+		}
+		return dot
+	}
+	cats, _ := f.Embed("Cats purr and cats sleep")
+	kittens, _ := f.Embed("cats sleep all day")
+	taxes, _ := f.Embed("quarterly tax filing deadline")
+	if len(cats) != FakeDims || cosine(cats, kittens) <= cosine(cats, taxes) {
+		t.Errorf("Shared words should mean closer vectors: %v vs %v", cosine(cats, kittens), cosine(cats, taxes))
+	}
+
+	f.Reply = func(string) (string, error) { return "uno dos tres", nil }
+	var n int
+	if full, err := f.Stream("x", func(string) error { n++; return nil }); err != nil || full != "uno dos tres" || n != 3 {
+		t.Errorf("Stream = %q (%d chunks), %v", full, n, err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, name := range Backends {
+		if _, err := New(Options{Backend: name}); err != nil {
+			t.Errorf("New(%s): %v", name, err)
+		}
+	}
+	if _, err := New(Options{Backend: "gpt"}); err == nil {
+		t.Error("Expected error for unknown backend")
+	}
+	if c, _ := New(Options{Backend: "openai"}); c.(*OpenAI).BaseURL != "http://localhost:8080/v1" {
+		t.Errorf("Unexpected default URL: %s", c.(*OpenAI).BaseURL)
+	}
+}
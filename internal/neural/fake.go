@@ -0,0 +1,89 @@
+package neural
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// FakeDims is the length of Fake embeddings.
+const FakeDims = 64
+
+// Fake is a deterministic in-process Backend for tests and offline runs:
+// the same prompt always gets the same answer, and texts sharing words get
+// similar embeddings.
+type Fake struct {
+	Reply func(prompt string) (string, error) // nil = a fixed reply derived from the prompt
+
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (f *Fake) Generate(prompt string) (string, error) {
+	f.mu.Lock()
+	f.prompts = append(f.prompts, prompt)
+	f.mu.Unlock()
+
+	if f.Reply != nil {
+		return f.Reply(prompt)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(prompt))
+	return fmt.Sprintf("fake reply %08x", h.Sum32()), nil
+}
+
+// GenerateJSON ignores the schema: Reply decides what the "model" answers,
+// so tests can feed invalid JSON too.
+func (f *Fake) GenerateJSON(prompt string, schema json.RawMessage) (string, error) {
+	return f.Generate(prompt)
+}
+
+// Stream delivers the Generate reply word by word.
+func (f *Fake) Stream(prompt string, onChunk func(string) error) (string, error) {
+	reply, err := f.Generate(prompt)
+	if err != nil {
+		return "", err
+	}
+	for _, word := range strings.SplitAfter(reply, " ") {
+		if err := onChunk(word); err != nil {
+			return reply, err
+		}
+	}
+	return reply, nil
+}
+
+// Embed hashes each lowercase word into one of FakeDims buckets and
+// normalizes the counts, so cosine similarity tracks shared vocabulary.
+func (f *Fake) Embed(text string) ([]float32, error) {
+	vec := make([]float32, FakeDims)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		h := fnv.New32a()
+		h.Write([]byte(w))
+		vec[h.Sum32()%FakeDims]++
+	}
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v * v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vec {
+			vec[i] *= scale
+		}
+	}
+	return vec, nil
+}
+
+// Prompts returns every prompt received so far, oldest first.
+func (f *Fake) Prompts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.prompts...)
+}
@@ -1,105 +1,135 @@
 package neural
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 )
 
-type Client struct {
-	BaseURL string
-	Model   string
+// Ollama talks to a local Ollama server (/api/generate, /api/embeddings).
+type Ollama struct {
+	BaseURL    string
+	Model      string
+	EmbedModel string // "" = Model
+	HTTP       *http.Client
 }
 
-func NewClient(url, model string) *Client {
+func NewOllama(url, model string) *Ollama {
 	if url == "" {
 		url = "http://localhost:11434"
 	}
 	if model == "" {
 		model = "llama3"
 	} // Default model
-	return &Client{BaseURL: url, Model: model}
+	return &Ollama{BaseURL: url, Model: model, HTTP: &http.Client{Timeout: DefaultTimeout}}
 }
 
 type CompletionRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
+	Model  string          `json:"model"`
+	Prompt string          `json:"prompt"`
+	Stream bool            `json:"stream"`
+	Format json.RawMessage `json:"format,omitempty"` // JSON schema for structured output
 }
 
+// CompletionResponse is the whole answer, or one line of a streamed one.
 type CompletionResponse struct {
 	Response string `json:"response"`
+	Done     bool   `json:"done"`
+	Error    string `json:"error,omitempty"`
 }
 
-// Low-level generate
-func (c *Client) Generate(prompt string) (string, error) {
-	reqBody := CompletionRequest{
-		Model:  c.Model,
-		Prompt: prompt,
-		Stream: false,
+func (c *Ollama) post(path string, body interface{}) (*http.Response, error) {
+	jsonBody, _ := json.Marshal(body)
+	resp, err := c.HTTP.Post(c.BaseURL+path, "application/json", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("ollama connection failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, httpError("ollama", resp)
 	}
+	return resp, nil
+}
+
+func (c *Ollama) Generate(prompt string) (string, error) {
+	return c.generate(CompletionRequest{Model: c.Model, Prompt: prompt})
+}
+
+// GenerateJSON passes the schema as Ollama's "format", which constrains decoding.
+func (c *Ollama) GenerateJSON(prompt string, schema json.RawMessage) (string, error) {
+	return c.generate(CompletionRequest{Model: c.Model, Prompt: prompt, Format: schema})
+}
 
-	jsonBody, _ := json.Marshal(reqBody)
-	resp, err := http.Post(c.BaseURL+"/api/generate", "application/json", bytes.NewBuffer(jsonBody))
+func (c *Ollama) generate(req CompletionRequest) (string, error) {
+	resp, err := c.post("/api/generate", req)
 	if err != nil {
-		return "", fmt.Errorf("ollama connection failed: %w", err)
+		return "", err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("ollama error: %s", resp.Status)
-	}
-
-	body, _ := io.ReadAll(resp.Body)
 	var result CompletionResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", err
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("ollama: bad response: %w", err)
 	}
-
 	return result.Response, nil
 }
 
-// Skills
-
-func (c *Client) Summarize(content string) (string, error) {
-	prompt := fmt.Sprintf(`Task: Summarize the following note content in less than 500 chars.
-Context: Zettelkasten note.
-Content:
-%s
-Summary:`, content)
-	return c.Generate(prompt)
-}
+// Stream reads Ollama's newline-delimited JSON chunks until "done".
+func (c *Ollama) Stream(prompt string, onChunk func(string) error) (string, error) {
+	resp, err := c.post("/api/generate", CompletionRequest{Model: c.Model, Prompt: prompt, Stream: true})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
 
-func (c *Client) SuggestCues(content string) (string, error) {
-	prompt := fmt.Sprintf(`Task: Generate 3 active recall questions based on the content.
-Constraint: Each question MUST end with a question mark '?'. Max 120 chars each.
-Content:
-%s
-Questions:`, content)
-	return c.Generate(prompt)
+	var full bytes.Buffer
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var chunk CompletionResponse
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			return full.String(), fmt.Errorf("ollama: bad stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return full.String(), fmt.Errorf("ollama error: %s", chunk.Error)
+		}
+		if chunk.Response != "" {
+			full.WriteString(chunk.Response)
+			if err := onChunk(chunk.Response); err != nil {
+				return full.String(), err
+			}
+		}
+		if chunk.Done {
+			break
+		}
+	}
+	return full.String(), scanner.Err()
 }
 
-func (c *Client) Draft(topic string) (string, error) {
-	prompt := fmt.Sprintf(`Task: Generate a draft note about "%s".
-Format: Strict Markdown.
-Structure:
-# Title
-Fecha: YYYY-MM-DD
-Tipo: idea
-
-## Notas
-(Content)
-
-## Cues
-- Question?
-
-## Resumen
-(Summary)
+func (c *Ollama) Embed(text string) ([]float32, error) {
+	model := c.EmbedModel
+	if model == "" {
+		model = c.Model
+	}
+	resp, err := c.post("/api/embeddings", map[string]string{"model": model, "prompt": text})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-## Enlaces
-- [[related]]
-`, topic)
-	return c.Generate(prompt)
+	var result struct {
+		Embedding []float32 `json:"embedding"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("ollama: bad embedding response: %w", err)
+	}
+	if len(result.Embedding) == 0 {
+		return nil, fmt.Errorf("ollama: no embedding returned (does %s support embeddings?)", model)
+	}
+	return result.Embedding, nil
 }
@@ -0,0 +1,181 @@
+package neural
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// OpenAI talks to any server implementing the OpenAI chat API
+// (/chat/completions, /embeddings): llama.cpp server, vLLM, LM Studio...
+// BaseURL includes the version prefix, e.g. http://localhost:8080/v1.
+type OpenAI struct {
+	BaseURL    string
+	Model      string
+	EmbedModel string // "" = Model
+	APIKey     string // Optional; local servers usually ignore it
+	HTTP       *http.Client
+}
+
+func NewOpenAI(url, model, apiKey string) *OpenAI {
+	if url == "" {
+		url = "http://localhost:8080/v1"
+	}
+	if model == "" {
+		model = "default" // llama.cpp serves its one loaded model under any name
+	}
+	return &OpenAI{BaseURL: strings.TrimSuffix(url, "/"), Model: model, APIKey: apiKey, HTTP: &http.Client{Timeout: DefaultTimeout}}
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	Stream         bool            `json:"stream"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type responseFormat struct {
+	Type       string `json:"type"` // "json_schema"
+	JSONSchema struct {
+		Name   string          `json:"name"`
+		Schema json.RawMessage `json:"schema"`
+		Strict bool            `json:"strict"`
+	} `json:"json_schema"`
+}
+
+// chatResponse covers both the full answer (Message) and SSE chunks (Delta).
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+		Delta   chatMessage `json:"delta"`
+	} `json:"choices"`
+}
+
+func (c *OpenAI) post(path string, body interface{}) (*http.Response, error) {
+	jsonBody, _ := json.Marshal(body)
+	req, err := http.NewRequest(http.MethodPost, c.BaseURL+path, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("openai connection failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, httpError("openai", resp)
+	}
+	return resp, nil
+}
+
+func (c *OpenAI) chat(prompt string, stream bool, format *responseFormat) (*http.Response, error) {
+	return c.post("/chat/completions", chatRequest{
+		Model:          c.Model,
+		Messages:       []chatMessage{{Role: "user", Content: prompt}},
+		Stream:         stream,
+		ResponseFormat: format,
+	})
+}
+
+func (c *OpenAI) Generate(prompt string) (string, error) {
+	return c.generate(prompt, nil)
+}
+
+// GenerateJSON uses response_format "json_schema" (llama.cpp server, vLLM, LM Studio).
+func (c *OpenAI) GenerateJSON(prompt string, schema json.RawMessage) (string, error) {
+	format := &responseFormat{Type: "json_schema"}
+	format.JSONSchema.Name = "answer"
+	format.JSONSchema.Schema = schema
+	format.JSONSchema.Strict = true
+	return c.generate(prompt, format)
+}
+
+func (c *OpenAI) generate(prompt string, format *responseFormat) (string, error) {
+	resp, err := c.chat(prompt, false, format)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("openai: bad response: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("openai: empty response")
+	}
+	return result.Choices[0].Message.Content, nil
+}
+
+// Stream reads server-sent events ("data: {...}") until "data: [DONE]".
+func (c *OpenAI) Stream(prompt string, onChunk func(string) error) (string, error) {
+	resp, err := c.chat(prompt, true, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue // Blank separators, comments, event names
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var chunk chatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return full.String(), fmt.Errorf("openai: bad stream chunk: %w", err)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		text := chunk.Choices[0].Delta.Content
+		full.WriteString(text)
+		if err := onChunk(text); err != nil {
+			return full.String(), err
+		}
+	}
+	return full.String(), scanner.Err()
+}
+
+func (c *OpenAI) Embed(text string) ([]float32, error) {
+	model := c.EmbedModel
+	if model == "" {
+		model = c.Model
+	}
+	resp, err := c.post("/embeddings", map[string]string{"model": model, "input": text})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("openai: bad embedding response: %w", err)
+	}
+	if len(result.Data) == 0 || len(result.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("openai: no embedding returned for model %s", model)
+	}
+	return result.Data[0].Embedding, nil
+}
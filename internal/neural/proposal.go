@@ -0,0 +1,295 @@
+package neural
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+)
+
+// Proposals are model outputs parsed into the shape of a note field and
+// checked against the note limits, so the bot can offer to apply them as is.
+
+// SummaryProposal is a ## Resumen body that passes limits.CheckResumen.
+type SummaryProposal struct {
+	Text  string
+	Fixes []string // What was done to make it valid (retried, trimmed)
+}
+
+// Rejection is a model output that could not be used, and why.
+type Rejection struct {
+	Text   string
+	Reason string
+}
+
+// CuesProposal holds cues that pass limits.CheckCue and fit in the note.
+type CuesProposal struct {
+	Cues     []string
+	Rejected []Rejection
+	Fixes    []string
+}
+
+func summaryPrompt(content string, limits markdown.Limits) string {
+	return fmt.Sprintf(`Task: Summarize the following note content in less than %d chars.
+Context: Zettelkasten note.
+Content:
+%s
+Summary:`, limits.ResumenChars, content)
+}
+
+func cuesPrompt(content string, n int, limits markdown.Limits) string {
+	return fmt.Sprintf(`Task: Generate %d active recall questions based on the content.
+Constraint: Each question MUST end with a question mark '?'. Max %d chars each.
+Content:
+%s
+Questions:`, n, limits.CueLen, content)
+}
+
+// retryPrompt re-asks with the problems of the previous answer.
+func retryPrompt(prompt, previous string, problems []string) string {
+	return fmt.Sprintf(`%s
+
+Your previous answer was rejected:
+%s
+Problems:
+- %s
+Answer again, fixing every problem. Output only the answer.`, prompt, previous, strings.Join(problems, "\n- "))
+}
+
+// ProposeSummary asks for a summary of content as {"resumen": ...}. An answer
+// that is not valid JSON or breaks the limits is re-asked (see MaxAttempts);
+// if the last one is still too long it is trimmed at a sentence or word boundary.
+func ProposeSummary(ai Backend, content string, limits markdown.Limits) (*SummaryProposal, error) {
+	prompt := summaryPrompt(content, limits) + "\nAnswer as JSON: {\"resumen\": \"<summary>\"}"
+	out, res, err := generateJSON(ai, prompt, summarySchema(limits), func(s *summaryJSON) []string {
+		if err := limits.CheckResumen(s.Resumen); err != nil {
+			return []string{err.Error()}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	p := &SummaryProposal{Fixes: res.Retries}
+	if len(res.Problems) == 0 {
+		p.Text = strings.TrimSpace(out.Resumen)
+		return p, nil
+	}
+
+	// Out of attempts: salvage the last answer, even if it wasn't JSON
+	text := ParseSummary(res.Reply)
+	if out != nil {
+		text = ParseSummary(out.Resumen)
+	}
+	if text == "" {
+		return nil, fmt.Errorf("no usable summary after %d attempt(s): %s", MaxAttempts, strings.Join(res.Problems, "; "))
+	}
+	p.Text = text
+	if n := utf8.RuneCountInString(text); n > limits.ResumenChars {
+		p.Text = trimToLimit(text, limits.ResumenChars)
+		p.Fixes = append(p.Fixes, fmt.Sprintf("trimmed from %d to %d chars", n, utf8.RuneCountInString(p.Text)))
+	}
+	return p, nil
+}
+
+// ProposeCues asks for new cues as {"cues": [...]}, as many as fit next to
+// the existing ones (at most 3). Invalid cues are rejected with a reason; if
+// that leaves fewer than asked, the model is re-asked for the missing ones
+// only. Rejected holds the last attempt's rejections.
+func ProposeCues(ai Backend, content string, existing int, limits markdown.Limits) (*CuesProposal, error) {
+	room := limits.CuesCount - existing
+	p := &CuesProposal{}
+	if room <= 0 {
+		return p, nil
+	}
+	want := min(3, room)
+
+	accepted := map[string]bool{}
+	accept := func(cues []string) []string {
+		var problems []string
+		// Earlier attempts' reasons are for cues never offered; a cue the
+		// model repeats is judged (and reported) again
+		p.Rejected = nil
+		seen := map[string]bool{}
+		for _, cue := range cues {
+			cue = strings.TrimSpace(cue)
+			key := strings.ToLower(cue)
+			switch err := limits.CheckCue(cue); {
+			case cue == "" || seen[key] || accepted[key]:
+				continue
+			case err != nil:
+				p.Rejected = append(p.Rejected, Rejection{Text: cue, Reason: err.Error()})
+				problems = append(problems, fmt.Sprintf("%q: %v", cue, err))
+			case len(p.Cues) >= room:
+				p.Rejected = append(p.Rejected, Rejection{Text: cue, Reason: fmt.Sprintf("the note has room for %d more cue(s)", room)})
+			default:
+				p.Cues = append(p.Cues, cue)
+				accepted[key] = true
+			}
+			seen[key] = true
+		}
+		if len(p.Cues) < want {
+			problems = append(problems, fmt.Sprintf("%d valid question(s) so far, %d needed", len(p.Cues), want))
+		}
+		return problems
+	}
+
+	request := func() (string, json.RawMessage) {
+		n := want - len(p.Cues)
+		return cuesPrompt(content, n, limits) + "\nAnswer as JSON: {\"cues\": [\"<question>?\", ...]}", cuesSchema(n, limits)
+	}
+	out, res, err := generateJSONFunc(ai, request, func(c *cuesJSON) []string {
+		return accept(c.Cues)
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.Fixes = res.Retries
+	if out == nil {
+		// Never got JSON: fall back to reading the last answer as a list
+		accept(ParseCues(res.Reply))
+	}
+	return p, nil
+}
+
+// DraftProposal is a complete note that passes markdown validation.
+type DraftProposal struct {
+	Title   string
+	Content []byte // Rendered with the note template
+	Fixes   []string
+	Dropped []string // Links the model returned that are not existing notes
+}
+
+func draftPrompt(topic string, limits markdown.Limits) string {
+	return fmt.Sprintf(`Task: Write a Zettelkasten note about "%s".
+Fields:
+- title: short title, one line (max %d chars)
+- notas: the note body as Markdown paragraphs or bullets, no headings (max %d chars)
+- cues: 3 active recall questions, each ending with '?' (max %d chars each)
+- resumen: a 1-3 sentence summary (max %d chars)
+- links: IDs of related notes in kebab-case, may be empty (IDs that are not existing notes are dropped)
+Answer as JSON with exactly these fields.`, topic, limits.TitleChars, limits.NotasChars, limits.CueLen, limits.ResumenChars)
+}
+
+// ProposeDraft asks for a note about topic as JSON, renders it with the note
+// template (Fecha = day, Tipo = category) and validates it like any note
+// (DefaultMode, DefaultLimits). Each violation goes back to the model; after
+// MaxAttempts the last violations are returned as the error.
+// Only links for which known returns true are kept (nil keeps none): the
+// model has not seen the vault, so any other ID would be a dangling link.
+func ProposeDraft(ai Backend, topic, category string, day time.Time, known func(id string) bool) (*DraftProposal, error) {
+	limits := markdown.DefaultLimits
+	var content []byte
+	var dropped []string
+	out, res, err := generateJSON(ai, draftPrompt(topic, limits), draftSchema(limits), func(d *draftJSON) []string {
+		content, dropped = renderDraft(d, category, day, known)
+		_, report := markdown.ValidateMode("draft.md", content, markdown.DefaultMode)
+		var problems []string
+		for _, v := range report.Violations {
+			if v.Severity == markdown.SeverityError {
+				problems = append(problems, fmt.Sprintf("%s: %s", v.Code, v.Message))
+			}
+		}
+		return problems
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Problems) > 0 {
+		return nil, fmt.Errorf("draft still invalid after %d attempt(s):\n- %s", MaxAttempts, strings.Join(res.Problems, "\n- "))
+	}
+	return &DraftProposal{Title: out.Title, Content: content, Fixes: res.Retries, Dropped: dropped}, nil
+}
+
+// renderDraft fills the note template with the decoded fields and returns
+// the links it left out because known rejected them.
+func renderDraft(d *draftJSON, category string, day time.Time, known func(string) bool) (content []byte, dropped []string) {
+	d.Title = strings.Join(strings.Fields(d.Title), " ")
+	doc := markdown.NewDocument(d.Title, day.Format("2006-01-02"), category, strings.TrimSpace(d.Notas))
+	for _, cue := range d.Cues {
+		if cue = strings.Join(strings.Fields(cue), " "); cue != "" {
+			doc.AddCue(cue)
+		}
+	}
+	doc.SetSection("Resumen", d.Resumen)
+	for _, link := range d.Links {
+		link = strings.Trim(strings.TrimSpace(link), "[]")
+		switch {
+		case link == "":
+		case known != nil && known(link):
+			doc.AddLink(link)
+		default:
+			dropped = append(dropped, link)
+		}
+	}
+	return doc.Render(), dropped
+}
+
+var (
+	reCueMarker    = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)]|Q\d*:)\s*`)
+	reSummaryLabel = regexp.MustCompile(`(?i)^\s*(?:\*\*)?(?:summary|resumen)(?:\*\*)?\s*:\s*`)
+)
+
+// ParseCues extracts questions from a free-form answer: one per line, without
+// list markers, numbering, quotes or bold. If any line is a list item, only
+// list items count (the rest is chatter such as "Here are 3 questions:").
+func ParseCues(reply string) []string {
+	var items, plain []string
+	for _, line := range strings.Split(reply, "\n") {
+		line = strings.TrimSpace(strings.Trim(strings.TrimSpace(line), "`"))
+		if line == "" || strings.HasSuffix(line, ":") {
+			continue
+		}
+		marked := reCueMarker.MatchString(line)
+		line = strings.Trim(reCueMarker.ReplaceAllString(line, ""), `*"“”' `)
+		if line == "" {
+			continue
+		}
+		if marked {
+			items = append(items, line)
+		} else {
+			plain = append(plain, line)
+		}
+	}
+	if len(items) > 0 {
+		return items
+	}
+	return plain
+}
+
+// ParseSummary strips code fences, a leading "Summary:" label and surrounding
+// quotes, and joins the answer into one paragraph.
+func ParseSummary(reply string) string {
+	var lines []string
+	for _, line := range strings.Split(reply, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	text := strings.Join(strings.Fields(strings.Join(lines, "\n")), " ")
+	text = reSummaryLabel.ReplaceAllString(text, "")
+	return strings.Trim(text, `"“” `)
+}
+
+// trimToLimit cuts text to at most max runes, at the last sentence end if
+// one is past the middle, else at a word boundary with an ellipsis.
+func trimToLimit(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	cut := string(runes[:max])
+	if i := strings.LastIndexAny(cut, ".!?"); i >= len(cut)/2 {
+		return cut[:i+1]
+	}
+	cut = string(runes[:max-1])
+	if i := strings.LastIndex(cut, " "); i > 0 && runes[max-1] != ' ' {
+		cut = cut[:i] // Don't split a word
+	}
+	return strings.TrimRight(cut, " ,;:") + "…"
+}
@@ -0,0 +1,145 @@
+package neural
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+)
+
+// scripted replies with answers in order, repeating the last one.
+func scripted(answers ...string) *Fake {
+	n := 0
+	return &Fake{Reply: func(string) (string, error) {
+		reply := answers[min(n, len(answers)-1)]
+		n++
+		return reply, nil
+	}}
+}
+
+func TestProposeSummary(t *testing.T) {
+	limits := markdown.SpecLimits
+	limits.ResumenChars = 40
+
+	ai := scripted(`{"resumen": "**Short and valid.**"}`)
+	p, err := ProposeSummary(ai, "note", limits)
+	if err != nil || p.Text != "**Short and valid.**" || len(p.Fixes) != 0 {
+		t.Errorf("Unexpected proposal: %+v, %v", p, err)
+	}
+
+	long := "First sentence is fine. Second sentence pushes it well over the limit."
+	ai = scripted(`{"resumen": "`+long+`"}`, "```json\n{\"resumen\": \""+long+"\"}\n```")
+	p, err = ProposeSummary(ai, "note", limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Text != "First sentence is fine." || len(p.Fixes) != MaxAttempts || !strings.HasPrefix(p.Fixes[MaxAttempts-1], "trimmed from") {
+		t.Errorf("Expected retries then a trim: %+v", p)
+	}
+	if prompts := ai.Prompts(); len(prompts) != MaxAttempts || !strings.Contains(prompts[1], "summary has 70 chars (max 40)") {
+		t.Errorf("Retry should carry the violation: %v", prompts)
+	}
+
+	// No JSON at all: the last plain answer is still usable
+	p, err = ProposeSummary(scripted("Summary: Plain answer."), "note", limits)
+	if err != nil || p.Text != "Plain answer." {
+		t.Errorf("Expected the plain answer as fallback: %+v, %v", p, err)
+	}
+
+	if got := trimToLimit("no sentence end here at all", 12); got != "no sentence…" {
+		t.Errorf("Word-boundary trim: %q", got)
+	}
+}
+
+func TestProposeCues(t *testing.T) {
+	limits := markdown.SpecLimits
+	limits.CueLen = 30
+
+	ai := scripted(
+		`{"cues": ["What is spacing?", "Why does it work", "`+strings.Repeat("very ", 10)+`long?"]}`,
+		`{"cues": ["Why does spacing work?", "What is spacing?", "When to review?"]}`,
+	)
+	p, err := ProposeCues(ai, "note", 5, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Cues) != 2 || p.Cues[0] != "What is spacing?" || p.Cues[1] != "Why does spacing work?" {
+		t.Errorf("Expected room for 2 cues, deduplicated: %+v", p.Cues)
+	}
+	reasons := []string{}
+	for _, r := range p.Rejected {
+		reasons = append(reasons, r.Reason)
+	}
+	// Only the last attempt's rejections: the first answer's were never offered
+	want := []string{"the note has room for 2 more cue(s)"}
+	if strings.Join(reasons, "|") != strings.Join(want, "|") {
+		t.Errorf("Unexpected rejections: %+v", p.Rejected)
+	}
+	prompts := ai.Prompts()
+	if len(prompts) != 2 || len(p.Fixes) != 1 {
+		t.Fatalf("Expected exactly one retry: %d prompts, fixes %v", len(prompts), p.Fixes)
+	}
+	if !strings.Contains(prompts[0], "Generate 2 ") || !strings.Contains(prompts[1], "Generate 1 ") {
+		t.Errorf("The retry should ask only for the missing cue:\n%s", prompts[1])
+	}
+
+	// A multi-line cue would become several list items: rejected and re-asked
+	ai = scripted(`{"cues": ["What?\n- Injected?"]}`, `{"cues": ["What is it?"]}`)
+	p, err = ProposeCues(ai, "note", limits.CuesCount-1, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Cues) != 1 || p.Cues[0] != "What is it?" || !strings.Contains(ai.Prompts()[1], "must be a single line") {
+		t.Errorf("Expected the multi-line cue rejected: %+v", p)
+	}
+
+	if p, _ := ProposeCues(scripted("1. What is spacing?"), "note", 5, limits); len(p.Cues) != 1 {
+		t.Errorf("A plain list should be read when JSON never comes: %+v", p)
+	}
+	if p, _ := ProposeCues(scripted("x?"), "note", limits.CuesCount, limits); len(p.Cues) != 0 {
+		t.Errorf("A full note gets no proposals: %+v", p)
+	}
+}
+
+func TestProposeDraft(t *testing.T) {
+	day := time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)
+	ai := scripted(
+		`{"title": "Spacing", "notas": "## Intro\nReviews spread out.", "cues": ["Why space reviews"], "resumen": "Spread reviews.", "links": []}`,
+		`{"title": "Spacing", "notas": "Reviews spread out.", "cues": ["Why space reviews?"], "resumen": "Spread reviews.", "links": ["[[memory]]", "invented"]}`,
+	)
+	known := func(id string) bool { return id == "memory" }
+	p, err := ProposeDraft(ai, "spaced repetition", "libro", day, known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "Spacing" || len(p.Fixes) != 1 {
+		t.Errorf("Expected one retry: %+v", p)
+	}
+	note, err := markdown.Parse("draft.md", p.Content)
+	if err != nil {
+		t.Fatalf("Draft should validate: %v\n%s", err, p.Content)
+	}
+	for _, want := range []string{"Fecha: 2024-02-02\nTipo: libro\n", "- Why space reviews?\n", "- [[memory]]\n"} {
+		if !strings.Contains(string(p.Content), want) {
+			t.Errorf("Draft missing %q:\n%s", want, p.Content)
+		}
+	}
+	if strings.Contains(string(p.Content), "[[invented]]") || len(p.Dropped) != 1 || p.Dropped[0] != "invented" {
+		t.Errorf("Expected the unknown link dropped: %v\n%s", p.Dropped, p.Content)
+	}
+	if note.Title != "Spacing" {
+		t.Errorf("Unexpected title: %q", note.Title)
+	}
+	if prompts := ai.Prompts(); len(prompts) != 2 || !strings.Contains(prompts[1], "Problems:") || !strings.Contains(prompts[0], `"spaced repetition"`) {
+		t.Errorf("Retry should carry the violations: %v", prompts)
+	}
+
+	ai = scripted(`{"title": "", "notas": "", "cues": [], "resumen": "", "links": []}`)
+	if _, err := ProposeDraft(ai, "nothing", "idea", day, known); err == nil || !strings.Contains(err.Error(), "still invalid") {
+		t.Errorf("Expected an invalid draft error, got %v", err)
+	}
+	if n := len(ai.Prompts()); n != MaxAttempts {
+		t.Errorf("Expected %d attempts, got %d", MaxAttempts, n)
+	}
+}
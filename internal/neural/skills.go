@@ -0,0 +1,70 @@
+package neural
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+)
+
+// Skills: prompts for the note workflows, usable with any Backend.
+
+func Summarize(ai Backend, content string) (string, error) {
+	return ai.Generate(summaryPrompt(content, markdown.DefaultLimits))
+}
+
+// reListMarker matches bullets and "1." / "2)" numbering the model may add.
+var reListMarker = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s+`)
+
+// LinkCandidate is a note offered to JustifyLinks.
+type LinkCandidate struct {
+	ID      string
+	Title   string
+	Excerpt string
+}
+
+// JustifyLinks asks for a one-line reason per candidate. The result maps
+// candidate IDs to reasons; candidates the model calls unrelated ("skip") or
+// leaves out are absent.
+func JustifyLinks(ai Backend, note string, candidates []LinkCandidate) (map[string]string, error) {
+	sb := strings.Builder{}
+	for _, cand := range candidates {
+		sb.WriteString(fmt.Sprintf("\n[%s] %s\n%s\n", cand.ID, cand.Title, cand.Excerpt))
+	}
+	prompt := fmt.Sprintf(`Task: For each candidate note, explain in ONE line why the source note should link to it.
+Format: exactly one line per candidate, "<id>: <reason>", reason under 100 chars.
+Write "<id>: skip" if the candidate is unrelated.
+Source note:
+%s
+Candidates:
+%s
+Reasons:`, note, sb.String())
+
+	reply, err := ai.Generate(prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[string]bool{}
+	for _, cand := range candidates {
+		known[cand.ID] = true
+	}
+	reasons := map[string]string{}
+	for _, line := range strings.Split(reply, "\n") {
+		line = reListMarker.ReplaceAllString(line, "")
+		id, reason, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		id = strings.Trim(strings.TrimSpace(id), "[]`*\"")
+		reason = strings.TrimSpace(reason)
+		if !known[id] || reason == "" || strings.EqualFold(strings.Trim(reason, "."), "skip") {
+			continue
+		}
+		if _, seen := reasons[id]; !seen {
+			reasons[id] = reason
+		}
+	}
+	return reasons, nil
+}
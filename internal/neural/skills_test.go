@@ -0,0 +1,23 @@
+package neural
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJustifyLinks(t *testing.T) {
+	ai := &Fake{Reply: func(prompt string) (string, error) {
+		return "Here you go:\n1. [20240202-alpha]: Same method.\n- `20240202-beta`: skip\n* 20240202-gamma: Extends it.\n20240202-gamma: duplicate\nghost: invented\n", nil
+	}}
+	candidates := []LinkCandidate{{ID: "20240202-alpha"}, {ID: "20240202-beta"}, {ID: "20240202-gamma"}}
+	reasons, err := JustifyLinks(ai, "source", candidates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reasons) != 2 || reasons["20240202-alpha"] != "Same method." || reasons["20240202-gamma"] != "Extends it." {
+		t.Errorf("Unexpected reasons: %v", reasons)
+	}
+	if prompt := ai.Prompts()[0]; !strings.Contains(prompt, "[20240202-beta]") || !strings.Contains(prompt, "source") {
+		t.Errorf("Prompt should list every candidate:\n%s", prompt)
+	}
+}
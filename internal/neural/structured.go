@@ -0,0 +1,113 @@
+package neural
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+)
+
+// Structured output: the model is asked for JSON matching a schema (Ollama
+// "format", OpenAI "response_format"), the answer is decoded into a typed
+// struct and checked; problems are sent back in a new prompt.
+
+// MaxAttempts bounds how many times a structured answer is asked for, first
+// try included. Set once at startup (config ai.max_attempts).
+var MaxAttempts = 3
+
+// attempts describes how a structured answer was obtained.
+type attempts struct {
+	Reply    string   // Last raw answer
+	Problems []string // What is still wrong with it (nil = valid)
+	Retries  []string // One line per re-prompt, for the user
+}
+
+// generateJSON asks for JSON matching schema and decodes it into a new T.
+// check lists what is wrong with a decoded answer (nil = accept). Returns the
+// last decoded answer (nil if none decoded) and the attempt history; err is
+// only for backend failures.
+func generateJSON[T any](ai Backend, prompt string, schema json.RawMessage, check func(*T) []string) (*T, attempts, error) {
+	return generateJSONFunc(ai, func() (string, json.RawMessage) { return prompt, schema }, check)
+}
+
+// generateJSONFunc is generateJSON with the prompt and schema rebuilt by
+// request before every attempt (e.g. to ask only for what is still missing).
+func generateJSONFunc[T any](ai Backend, request func() (string, json.RawMessage), check func(*T) []string) (*T, attempts, error) {
+	var res attempts
+	var last *T
+	prompt, schema := request()
+	ask := prompt
+	for attempt := 1; attempt <= max(MaxAttempts, 1); attempt++ {
+		reply, err := ai.GenerateJSON(ask, schema)
+		if err != nil {
+			return last, res, err
+		}
+		res.Reply = reply
+
+		out := new(T)
+		if err := json.Unmarshal([]byte(extractJSON(reply)), out); err != nil {
+			res.Problems = []string{fmt.Sprintf("the answer is not valid JSON for the schema (%v)", err)}
+		} else {
+			last = out
+			res.Problems = check(out)
+		}
+		if len(res.Problems) == 0 {
+			return last, res, nil
+		}
+		if attempt < MaxAttempts {
+			res.Retries = append(res.Retries, "retried: "+strings.Join(res.Problems, "; "))
+			prompt, schema = request()
+			ask = retryPrompt(prompt, reply, res.Problems)
+		}
+	}
+	return last, res, nil
+}
+
+// extractJSON drops code fences or chatter around the outermost JSON object.
+func extractJSON(reply string) string {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return reply
+	}
+	return reply[start : end+1]
+}
+
+type summaryJSON struct {
+	Resumen string `json:"resumen"`
+}
+
+type cuesJSON struct {
+	Cues []string `json:"cues"`
+}
+
+type draftJSON struct {
+	Title   string   `json:"title"`
+	Notas   string   `json:"notas"`
+	Cues    []string `json:"cues"`
+	Resumen string   `json:"resumen"`
+	Links   []string `json:"links"`
+}
+
+func schema(properties string, required ...string) json.RawMessage {
+	req, _ := json.Marshal(required)
+	return json.RawMessage(fmt.Sprintf(`{"type":"object","properties":{%s},"required":%s,"additionalProperties":false}`, properties, req))
+}
+
+func summarySchema(limits markdown.Limits) json.RawMessage {
+	return schema(fmt.Sprintf(`"resumen":{"type":"string","maxLength":%d}`, limits.ResumenChars), "resumen")
+}
+
+func cuesSchema(n int, limits markdown.Limits) json.RawMessage {
+	return schema(fmt.Sprintf(`"cues":{"type":"array","maxItems":%d,"items":{"type":"string","maxLength":%d}}`, n, limits.CueLen), "cues")
+}
+
+func draftSchema(limits markdown.Limits) json.RawMessage {
+	return schema(fmt.Sprintf(`"title":{"type":"string","maxLength":%d},`+
+		`"notas":{"type":"string","maxLength":%d},`+
+		`"cues":{"type":"array","maxItems":%d,"items":{"type":"string","maxLength":%d}},`+
+		`"resumen":{"type":"string","maxLength":%d},`+
+		`"links":{"type":"array","items":{"type":"string"}}`,
+		limits.TitleChars, limits.NotasChars, limits.CuesCount, limits.CueLen, limits.ResumenChars),
+		"title", "notas", "cues", "resumen", "links")
+}
@@ -0,0 +1,95 @@
+// Package review implements Active Recall: each "## Cues" item is a card
+// scheduled with SM-2. Scheduling state lives in review_cards (see index/migrations).
+package review
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+type Grade int
+
+const (
+	Again Grade = iota
+	Hard
+	Good
+	Easy
+)
+
+var gradeNames = []string{"Again", "Hard", "Good", "Easy"}
+
+func (g Grade) String() string {
+	if g < Again || g > Easy {
+		return fmt.Sprintf("Grade(%d)", int(g))
+	}
+	return gradeNames[g]
+}
+
+// ParseGrade accepts the grade name (case-insensitive) or its number 0-3.
+func ParseGrade(s string) (Grade, error) {
+	for i, name := range gradeNames {
+		if strings.EqualFold(s, name) || s == fmt.Sprint(i) {
+			return Grade(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown grade %q", s)
+}
+
+const (
+	DefaultEase = 2.5
+	MinEase     = 1.3
+
+	// A failed card comes back in the same session instead of tomorrow.
+	RelearnDelay = 10 * time.Minute
+)
+
+// State is the SM-2 scheduling state of one card.
+type State struct {
+	Ease     float64
+	Interval int // Days
+	Reps     int // Consecutive successful reviews
+	Lapses   int
+	Due      time.Time
+}
+
+// quality maps the 4 buttons onto SM-2's 0-5 scale.
+var quality = map[Grade]float64{Again: 1, Hard: 3, Good: 4, Easy: 5}
+
+// Schedule applies SM-2 for a review graded g at now.
+func Schedule(s State, g Grade, now time.Time) State {
+	if s.Ease == 0 {
+		s.Ease = DefaultEase
+	}
+
+	q := quality[g]
+	s.Ease = math.Max(MinEase, s.Ease+(0.1-(5-q)*(0.08+(5-q)*0.02)))
+
+	if g == Again {
+		s.Reps = 0
+		s.Lapses++
+		s.Interval = 0
+		s.Due = now.Add(RelearnDelay)
+		return s
+	}
+
+	switch {
+	case s.Reps == 0:
+		s.Interval = 1
+	case s.Reps == 1:
+		s.Interval = 6
+	default:
+		s.Interval = int(math.Round(float64(s.Interval) * s.Ease))
+	}
+	switch g {
+	case Hard:
+		s.Interval = max(1, int(math.Round(float64(s.Interval)*0.6)))
+	case Easy:
+		s.Interval = int(math.Round(float64(s.Interval) * 1.3))
+	}
+
+	s.Reps++
+	s.Due = now.AddDate(0, 0, s.Interval)
+	return s
+}
@@ -0,0 +1,57 @@
+package review
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	now := time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC)
+	s := State{Ease: DefaultEase}
+
+	// Good, Good, Good: 1d, 6d, then interval * ease
+	s = Schedule(s, Good, now)
+	if s.Interval != 1 || s.Reps != 1 || !s.Due.Equal(now.AddDate(0, 0, 1)) {
+		t.Fatalf("first review: %+v", s)
+	}
+	s = Schedule(s, Good, now)
+	if s.Interval != 6 {
+		t.Fatalf("second review: %+v", s)
+	}
+	s = Schedule(s, Good, now)
+	if s.Interval != 15 || s.Ease != DefaultEase {
+		t.Fatalf("third review: %+v", s)
+	}
+
+	// Again: back in the same session, streak and interval reset, ease drops
+	s = Schedule(s, Again, now)
+	if s.Reps != 0 || s.Lapses != 1 || s.Interval != 0 || !s.Due.Equal(now.Add(RelearnDelay)) || s.Ease >= DefaultEase {
+		t.Fatalf("lapse: %+v", s)
+	}
+
+	// Ease never drops below the SM-2 floor
+	for i := 0; i < 20; i++ {
+		s = Schedule(s, Again, now)
+	}
+	if s.Ease != MinEase {
+		t.Errorf("ease floor: %v", s.Ease)
+	}
+
+	// Easy grows faster than Good, Hard slower
+	base := State{Ease: DefaultEase, Interval: 6, Reps: 2}
+	hard, good, easy := Schedule(base, Hard, now), Schedule(base, Good, now), Schedule(base, Easy, now)
+	if !(hard.Interval < good.Interval && good.Interval < easy.Interval) {
+		t.Errorf("grade ordering: hard=%d good=%d easy=%d", hard.Interval, good.Interval, easy.Interval)
+	}
+}
+
+func TestParseGrade(t *testing.T) {
+	for in, want := range map[string]Grade{"again": Again, "Hard": Hard, "2": Good, "EASY": Easy} {
+		if g, err := ParseGrade(in); err != nil || g != want {
+			t.Errorf("%q: got %v, %v", in, g, err)
+		}
+	}
+	if _, err := ParseGrade("4"); err == nil {
+		t.Error("Expected error for out of range grade")
+	}
+}
@@ -0,0 +1,104 @@
+package review
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/eliseohh/zettelcornelbot/internal/index"
+)
+
+var (
+	ErrNoCardsDue = errors.New("no cards due")
+	// ErrNotDue: the card was already graded (double tap or an old review message).
+	ErrNotDue = errors.New("card is not due")
+)
+
+// Card is a cue ready to be asked.
+type Card struct {
+	ID     int64
+	NoteID string
+	Title  string
+	Cue    string
+	State
+}
+
+type Store struct {
+	db *index.DB
+}
+
+func NewStore(db *index.DB) *Store {
+	return &Store{db: db}
+}
+
+const cardQuery = `
+	SELECT r.id, r.node_id, COALESCE(n.title, r.node_id), c.text,
+	       r.ease, r.interval_days, r.reps, r.lapses, r.due
+	FROM review_cards r
+	JOIN nodes n ON n.id = r.node_id
+	JOIN cues c ON c.node_id = r.node_id AND c.hash = r.cue_hash`
+
+// Next returns the most overdue card, or ErrNoCardsDue.
+func (s *Store) Next(now time.Time) (*Card, error) {
+	row := s.db.QueryRow(cardQuery+` WHERE r.due <= ? ORDER BY r.due, r.id LIMIT 1`, now.Unix())
+	card, err := scanCard(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoCardsDue
+	}
+	return card, err
+}
+
+func (s *Store) Get(id int64) (*Card, error) {
+	return scanCard(s.db.QueryRow(cardQuery+` WHERE r.id = ?`, id))
+}
+
+// DueCount counts cards due at now.
+func (s *Store) DueCount(now time.Time) (int, error) {
+	var n int
+	err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM review_cards r
+		JOIN cues c ON c.node_id = r.node_id AND c.hash = r.cue_hash
+		WHERE r.due <= ?`, now.Unix()).Scan(&n)
+	return n, err
+}
+
+// Grade records a review and returns the rescheduled card.
+// ErrNotDue if the card is not due at now, so a repeated grade is a no-op.
+func (s *Store) Grade(id int64, g Grade, now time.Time) (*Card, error) {
+	card, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if card.Due.After(now) {
+		return nil, ErrNotDue
+	}
+
+	shown := card.Due.Unix()
+	card.State = Schedule(card.State, g, now)
+	// Matching the old due also rejects a concurrent grade of the same card
+	res, err := s.db.Exec(`
+		UPDATE review_cards
+		SET ease = ?, interval_days = ?, reps = ?, lapses = ?, due = ?, last_review = ?
+		WHERE id = ? AND due = ?`,
+		card.Ease, card.Interval, card.Reps, card.Lapses, card.Due.Unix(), now.Unix(), id, shown)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrNotDue
+	}
+	return card, nil
+}
+
+func scanCard(row *sql.Row) (*Card, error) {
+	var c Card
+	var due int64
+	err := row.Scan(&c.ID, &c.NoteID, &c.Title, &c.Cue, &c.Ease, &c.Interval, &c.Reps, &c.Lapses, &due)
+	if err != nil {
+		return nil, err
+	}
+	c.Due = time.Unix(due, 0)
+	return &c, nil
+}
@@ -0,0 +1,157 @@
+package review
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eliseohh/zettelcornelbot/internal/index"
+)
+
+func TestStoreKeepsHistoryAcrossEdits(t *testing.T) {
+	db, err := index.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	vault := t.TempDir()
+	path := filepath.Join(vault, "note.md")
+	write := func(notas string, cues ...string) {
+		content := "# Note\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\n" + notas + "\n\n## Cues\n"
+		for _, c := range cues {
+			content += "- " + c + "\n"
+		}
+		content += "\n## Resumen\n\n## Enlaces\n"
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	now := time.Now()
+	idx := index.NewIndexer(db)
+	idx.Now = func() time.Time { return now }
+	store := NewStore(db)
+
+	write("v1", "Keep me?", "Reword me?")
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := store.DueCount(now); n != 2 {
+		t.Fatalf("Expected 2 new cards due, got %d", n)
+	}
+
+	// Grade both
+	for i := 0; i < 2; i++ {
+		card, err := store.Next(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Grade(card.ID, Good, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Next(now); err != ErrNoCardsDue {
+		t.Fatalf("Expected nothing due, got %v", err)
+	}
+
+	// A second tap on an old message must not reschedule again
+	var first int64
+	db.QueryRow("SELECT MIN(id) FROM review_cards").Scan(&first)
+	before, _ := store.Get(first)
+	if _, err := store.Grade(first, Good, now); err != ErrNotDue {
+		t.Errorf("Expected ErrNotDue for a repeated grade, got %v", err)
+	}
+	if after, _ := store.Get(first); after.Interval != before.Interval || !after.Due.Equal(before.Due) {
+		t.Errorf("Repeated grade rescheduled the card: %+v -> %+v", before.State, after.State)
+	}
+
+	// Edit unrelated content and reword one cue
+	write("v2 with more notes", "Keep me?", "Reworded?")
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+
+	card, err := store.Next(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.Cue != "Reworded?" || card.Reps != 0 {
+		t.Errorf("Expected fresh card for reworded cue, got %+v", card)
+	}
+	if n, _ := store.DueCount(now); n != 1 {
+		t.Errorf("Untouched cue lost its schedule: %d due", n)
+	}
+
+	var total int
+	db.QueryRow("SELECT COUNT(*) FROM review_cards").Scan(&total)
+	if total != 2 {
+		t.Errorf("Expected old card dropped, got %d cards", total)
+	}
+}
+
+func TestStoreKeepsHistoryWhileNoteIsInvalid(t *testing.T) {
+	db, err := index.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	vault := t.TempDir()
+	path := filepath.Join(vault, "note.md")
+	valid := "# Note\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\n\n## Cues\n- Still here?\n\n## Resumen\n\n## Enlaces\n"
+	now := time.Now()
+	idx := index.NewIndexer(db)
+	idx.Now = func() time.Time { return now }
+	store := NewStore(db)
+
+	os.WriteFile(path, []byte(valid), 0644)
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+	card, err := store.Next(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graded, err := store.Grade(card.ID, Easy, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history := func() (ease float64, interval int) {
+		db.QueryRow("SELECT ease, interval_days FROM review_cards WHERE id = ?", card.ID).Scan(&ease, &interval)
+		return
+	}
+
+	// One bad save (sections missing), then the fix: each followed by a full Sync
+	os.WriteFile(path, []byte("# Note\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\nhalf-written\n"), 0644)
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+	if ease, interval := history(); ease != graded.Ease || interval != graded.Interval {
+		t.Fatalf("Invalid note lost its history: ease %v interval %d", ease, interval)
+	}
+
+	os.WriteFile(path, []byte(valid), 0644)
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+	if ease, interval := history(); ease != graded.Ease || interval != graded.Interval {
+		t.Errorf("Fixed note lost its history: ease %v interval %d", ease, interval)
+	}
+
+	// Only removing the file drops the cards
+	os.Remove(path)
+	if err := idx.Sync(vault); err != nil {
+		t.Fatal(err)
+	}
+	var total int
+	db.QueryRow("SELECT COUNT(*) FROM review_cards").Scan(&total)
+	if total != 0 {
+		t.Errorf("Deleted note kept %d card(s)", total)
+	}
+}
@@ -0,0 +1,217 @@
+// Package scheduler runs persistent timed jobs (reminders, pings, digests).
+// Jobs live in SQLite so they survive restarts; time comes from a Clock so
+// tests can drive Tick without sleeping.
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/eliseohh/zettelcornelbot/internal/index"
+)
+
+// DefaultTick is how often Start checks for due jobs.
+const DefaultTick = 30 * time.Second
+
+type Clock interface {
+	Now() time.Time
+}
+
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time { return time.Now() }
+
+type Job struct {
+	ID      int64
+	ChatID  int64
+	Kind    string // Selects the Handler
+	Spec    string // See ParseSpec
+	Payload string
+	NextRun time.Time
+}
+
+type Handler func(Job) error
+
+type Scheduler struct {
+	db        *index.DB
+	clock     Clock
+	defaultTZ *time.Location
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func New(db *index.DB, clock Clock, defaultTZ *time.Location) *Scheduler {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	if defaultTZ == nil {
+		defaultTZ = time.Local
+	}
+	return &Scheduler{db: db, clock: clock, defaultTZ: defaultTZ, handlers: make(map[string]Handler)}
+}
+
+// Handle registers the function run for jobs of a kind.
+func (s *Scheduler) Handle(kind string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[kind] = h
+}
+
+// Add persists a job and returns its ID. The first run is computed in the chat timezone.
+func (s *Scheduler) Add(chatID int64, kind, spec, payload string) (int64, error) {
+	parsed, err := ParseSpec(spec)
+	if err != nil {
+		return 0, err
+	}
+	next, ok := parsed.Next(s.clock.Now(), s.Timezone(chatID))
+	if !ok {
+		return 0, fmt.Errorf("spec %q never fires", spec)
+	}
+
+	res, err := s.db.Exec("INSERT INTO jobs (chat_id, kind, spec, payload, next_run) VALUES (?, ?, ?, ?, ?)",
+		chatID, kind, spec, payload, next.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (s *Scheduler) Cancel(id int64) error {
+	_, err := s.db.Exec("DELETE FROM jobs WHERE id = ?", id)
+	return err
+}
+
+// CancelKind removes every job of a kind for a chat.
+func (s *Scheduler) CancelKind(chatID int64, kind string) error {
+	_, err := s.db.Exec("DELETE FROM jobs WHERE chat_id = ? AND kind = ?", chatID, kind)
+	return err
+}
+
+// Jobs lists a chat's pending jobs by next run.
+func (s *Scheduler) Jobs(chatID int64) ([]Job, error) {
+	return s.query("SELECT id, chat_id, kind, spec, payload, next_run FROM jobs WHERE chat_id = ? ORDER BY next_run, id", chatID)
+}
+
+// SetTimezone stores the IANA zone (e.g. "America/Santiago") used for a chat's specs.
+func (s *Scheduler) SetTimezone(chatID int64, tz string) error {
+	if _, err := time.LoadLocation(tz); err != nil {
+		return fmt.Errorf("unknown timezone %q", tz)
+	}
+	_, err := s.db.Exec(`INSERT INTO chat_settings (chat_id, timezone) VALUES (?, ?)
+		ON CONFLICT(chat_id) DO UPDATE SET timezone = excluded.timezone`, chatID, tz)
+	return err
+}
+
+// Timezone returns the chat's zone, or the scheduler default.
+func (s *Scheduler) Timezone(chatID int64) *time.Location {
+	var tz string
+	if err := s.db.QueryRow("SELECT timezone FROM chat_settings WHERE chat_id = ?", chatID).Scan(&tz); err != nil {
+		return s.defaultTZ
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return s.defaultTZ
+	}
+	return loc
+}
+
+// Now is the scheduler clock in the chat timezone.
+func (s *Scheduler) Now(chatID int64) time.Time {
+	return s.clock.Now().In(s.Timezone(chatID))
+}
+
+// Tick runs every due job once. Recurring jobs are rescheduled from now, so a
+// long downtime yields a single catch-up run instead of a burst.
+func (s *Scheduler) Tick() error {
+	now := s.clock.Now()
+	due, err := s.query("SELECT id, chat_id, kind, spec, payload, next_run FROM jobs WHERE next_run <= ? ORDER BY next_run, id", now.Unix())
+	if err != nil {
+		return err
+	}
+
+	for _, job := range due {
+		s.mu.Lock()
+		h := s.handlers[job.Kind]
+		s.mu.Unlock()
+
+		if h == nil {
+			log.Printf("Scheduler: no handler for job %d (%s)", job.ID, job.Kind)
+		} else if err := h(job); err != nil {
+			log.Printf("Scheduler: job %d (%s) failed: %v", job.ID, job.Kind, err)
+		}
+
+		if err := s.reschedule(job, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) reschedule(job Job, now time.Time) error {
+	var next time.Time
+	ok := false
+	if spec, err := ParseSpec(job.Spec); err == nil {
+		next, ok = spec.Next(now, s.Timezone(job.ChatID))
+	}
+	if !ok {
+		// One-shot done (or spec no longer parses): drop it
+		return s.Cancel(job.ID)
+	}
+	// The handler may have cancelled its own job; UPDATE is then a no-op.
+	_, err := s.db.Exec("UPDATE jobs SET next_run = ? WHERE id = ?", next.Unix(), job.ID)
+	return err
+}
+
+// Start runs Tick every interval until Stop.
+func (s *Scheduler) Start(interval time.Duration) {
+	s.stop = make(chan struct{})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := s.Tick(); err != nil {
+				log.Printf("Scheduler tick failed: %v", err)
+			}
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) Stop() {
+	if s.stop != nil {
+		close(s.stop)
+		s.wg.Wait()
+	}
+}
+
+func (s *Scheduler) query(q string, args ...interface{}) ([]Job, error) {
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		var j Job
+		var next int64
+		if err := rows.Scan(&j.ID, &j.ChatID, &j.Kind, &j.Spec, &j.Payload, &next); err != nil {
+			return nil, err
+		}
+		j.NextRun = time.Unix(next, 0)
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
@@ -0,0 +1,151 @@
+package scheduler
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eliseohh/zettelcornelbot/internal/index"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) Now() time.Time          { return f.t }
+func (f *fakeClock) Advance(d time.Duration) { f.t = f.t.Add(d) }
+
+func newTestDB(t *testing.T) *index.DB {
+	t.Helper()
+	db, err := index.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestParseSpecNext(t *testing.T) {
+	loc, _ := time.LoadLocation("America/Santiago")
+	// Friday 2024-02-02 09:10 local
+	after := time.Date(2024, 2, 2, 9, 10, 0, 0, loc)
+
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"@every 30m", after.Add(30 * time.Minute)},
+		{"*/15 * * * *", time.Date(2024, 2, 2, 9, 15, 0, 0, loc)},
+		{"0 18 * * *", time.Date(2024, 2, 2, 18, 0, 0, 0, loc)},
+		{"0 9 * * 1-5", time.Date(2024, 2, 5, 9, 0, 0, 0, loc)}, // Next weekday morning: Monday
+		{"30 8 1 * *", time.Date(2024, 3, 1, 8, 30, 0, 0, loc)},
+		{"@daily", time.Date(2024, 2, 3, 0, 0, 0, 0, loc)},
+		{"@at 2024-02-02 20:00", time.Date(2024, 2, 2, 20, 0, 0, 0, loc)},
+	}
+	for _, tc := range cases {
+		spec, err := ParseSpec(tc.spec)
+		if err != nil {
+			t.Errorf("%q: %v", tc.spec, err)
+			continue
+		}
+		got, ok := spec.Next(after, loc)
+		if !ok || !got.Equal(tc.want) {
+			t.Errorf("%q: got %v (%v), want %v", tc.spec, got, ok, tc.want)
+		}
+	}
+
+	// One-shot in the past never fires
+	spec, _ := ParseSpec("@at 2024-02-02 08:00")
+	if _, ok := spec.Next(after, loc); ok {
+		t.Error("Expected past @at to be finished")
+	}
+
+	for _, bad := range []string{"", "* * *", "61 * * * *", "@every 10s", "@at tomorrow", "5-1 * * * *"} {
+		if _, err := ParseSpec(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestTick(t *testing.T) {
+	db := newTestDB(t)
+	clock := &fakeClock{t: time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC)}
+	s := New(db, clock, time.UTC)
+
+	var fired []string
+	s.Handle("message", func(j Job) error {
+		fired = append(fired, j.Payload)
+		return nil
+	})
+
+	if _, err := s.Add(1, "message", "@every 30m", "ping"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(1, "message", "@at 2024-02-02 09:45", "once"); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Tick()
+	if len(fired) != 0 {
+		t.Fatalf("Nothing should be due yet: %v", fired)
+	}
+
+	clock.Advance(30 * time.Minute)
+	s.Tick()
+	clock.Advance(15 * time.Minute)
+	s.Tick()
+	clock.Advance(15 * time.Minute)
+	s.Tick()
+	if want := []string{"ping", "once", "ping"}; len(fired) != 3 || fired[0] != want[0] || fired[1] != want[1] || fired[2] != want[2] {
+		t.Fatalf("Unexpected runs: %v", fired)
+	}
+
+	// Jobs survive a restart; the one-shot is gone
+	restarted := New(db, clock, time.UTC)
+	jobs, err := restarted.Jobs(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].Payload != "ping" || !jobs[0].NextRun.Equal(clock.Now().Add(30*time.Minute)) {
+		t.Fatalf("Unexpected persisted jobs: %+v", jobs)
+	}
+
+	// Long downtime: one catch-up run, not a burst
+	fired = nil
+	clock.Advance(5 * time.Hour)
+	restarted.Handle("message", func(j Job) error {
+		fired = append(fired, j.Payload)
+		return nil
+	})
+	restarted.Tick()
+	if len(fired) != 1 {
+		t.Errorf("Expected a single catch-up run, got %v", fired)
+	}
+}
+
+func TestChatTimezone(t *testing.T) {
+	db := newTestDB(t)
+	clock := &fakeClock{t: time.Date(2024, 2, 2, 12, 0, 0, 0, time.UTC)}
+	s := New(db, clock, time.UTC)
+
+	if err := s.SetTimezone(7, "Mars/Olympus"); err == nil {
+		t.Error("Expected unknown timezone error")
+	}
+	if err := s.SetTimezone(7, "Asia/Tokyo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 18:00 Tokyo is 09:00 UTC the next day; chat 8 keeps the default (UTC)
+	s.Add(7, "message", "0 18 * * *", "tokyo")
+	s.Add(8, "message", "0 18 * * *", "utc")
+
+	tokyo, _ := s.Jobs(7)
+	utc, _ := s.Jobs(8)
+	if !tokyo[0].NextRun.Equal(time.Date(2024, 2, 3, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Tokyo job at %v", tokyo[0].NextRun.UTC())
+	}
+	if !utc[0].NextRun.Equal(time.Date(2024, 2, 2, 18, 0, 0, 0, time.UTC)) {
+		t.Errorf("UTC job at %v", utc[0].NextRun.UTC())
+	}
+}
@@ -0,0 +1,195 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spec computes the next run of a job strictly after a given instant.
+// ok=false means the job is finished (one-shot already fired).
+type Spec interface {
+	Next(after time.Time, loc *time.Location) (next time.Time, ok bool)
+}
+
+// ParseSpec accepts:
+//
+//	@at 2024-02-02 18:00     one-shot, wall clock in the chat timezone (RFC3339 also accepted)
+//	@every 30m               fixed interval (time.ParseDuration)
+//	@hourly | @daily         shorthands for "0 * * * *" and "0 0 * * *"
+//	M H DoM Mon DoW          5-field cron: *, lists (1,5), ranges (1-5), steps (*/15)
+func ParseSpec(s string) (Spec, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "@at "):
+		return parseAt(strings.TrimSpace(strings.TrimPrefix(s, "@at ")))
+	case strings.HasPrefix(s, "@every "):
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(s, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval: %w", err)
+		}
+		if d < time.Minute {
+			return nil, fmt.Errorf("interval %s below 1m", d)
+		}
+		return everySpec(d), nil
+	case s == "@hourly":
+		return parseCron("0 * * * *")
+	case s == "@daily":
+		return parseCron("0 0 * * *")
+	}
+	return parseCron(s)
+}
+
+// -- One-shot --
+
+type atSpec struct {
+	wall    string // Parsed lazily in the chat timezone
+	instant time.Time
+	hasTZ   bool
+}
+
+var atLayouts = []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02 15:04:05"}
+
+func parseAt(s string) (Spec, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return atSpec{instant: t, hasTZ: true}, nil
+	}
+	for _, layout := range atLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return atSpec{wall: s}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid @at time %q (want YYYY-MM-DD HH:MM)", s)
+}
+
+func (a atSpec) Next(after time.Time, loc *time.Location) (time.Time, bool) {
+	t := a.instant
+	if !a.hasTZ {
+		for _, layout := range atLayouts {
+			if parsed, err := time.ParseInLocation(layout, a.wall, loc); err == nil {
+				t = parsed
+				break
+			}
+		}
+	}
+	if !t.After(after) {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// -- Interval --
+
+type everySpec time.Duration
+
+func (e everySpec) Next(after time.Time, _ *time.Location) (time.Time, bool) {
+	return after.Add(time.Duration(e)), true
+}
+
+// -- Cron --
+
+type cronSpec struct {
+	minute, hour, dom, month, dow fieldSet
+	domStar, dowStar              bool
+}
+
+type fieldSet map[int]bool
+
+func parseCron(s string) (Spec, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid spec %q: want 5 cron fields or @at/@every", s)
+	}
+
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+	sets := make([]fieldSet, 5)
+	for i, f := range fields {
+		set, err := parseField(f, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron field %q: %w", f, err)
+		}
+		sets[i] = set
+	}
+
+	return cronSpec{
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+		domStar: fields[2] == "*", dowStar: fields[4] == "*",
+	}, nil
+}
+
+func parseField(f string, lo, hi int) (fieldSet, error) {
+	set := fieldSet{}
+	for _, part := range strings.Split(f, ",") {
+		step := 1
+		if base, stepStr, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("bad step %q", stepStr)
+			}
+			step, part = n, base
+		}
+
+		from, to := lo, hi
+		if part != "*" {
+			a, b, isRange := strings.Cut(part, "-")
+			var err error
+			if from, err = strconv.Atoi(a); err != nil {
+				return nil, fmt.Errorf("bad value %q", a)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(b); err != nil {
+					return nil, fmt.Errorf("bad value %q", b)
+				}
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return nil, fmt.Errorf("out of range %d-%d", lo, hi)
+		}
+		for v := from; v <= to; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+func (c cronSpec) Next(after time.Time, loc *time.Location) (time.Time, bool) {
+	t := after.In(loc).Truncate(time.Minute).Add(time.Minute)
+	// Every valid spec matches within 4 years (Feb 29 worst case)
+	limit := t.AddDate(4, 0, 0)
+	for t.Before(limit) {
+		if !c.month[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.hour[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !c.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// Classic cron: if both day fields are restricted, either may match.
+func (c cronSpec) dayMatches(t time.Time) bool {
+	dom, dow := c.dom[t.Day()], c.dow[int(t.Weekday())]
+	switch {
+	case c.domStar && c.dowStar:
+		return true
+	case c.domStar:
+		return dow
+	case c.dowStar:
+		return dom
+	}
+	return dom || dow
+}
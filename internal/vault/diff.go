@@ -0,0 +1,64 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diff is a minimal line diff of two note versions: changed lines prefixed
+// with "-" / "+", unchanged ones omitted. Notes are small, so plain LCS is fine.
+func Diff(before, after string) string {
+	a, b := splitLines(before), splitLines(after)
+
+	// lcs[i][j] = LCS length of a[i:], b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	sb := strings.Builder{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			fmt.Fprintf(&sb, "+%s\n", b[j])
+			j++
+		default:
+			fmt.Fprintf(&sb, "-%s\n", a[i])
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// DiffStat counts added and removed lines of a Diff.
+func DiffStat(diff string) (added, removed int) {
+	for _, line := range splitLines(diff) {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
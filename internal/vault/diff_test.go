@@ -0,0 +1,23 @@
+package vault
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	before := "# T\n\n## Cues\n- A?\n\n## Enlaces\n"
+	after := "# T\n\n## Cues\n- A?\n- B?\n\n## Enlaces\n- [[x]]\n"
+
+	diff := Diff(before, after)
+	if diff != "+- B?\n+- [[x]]\n" {
+		t.Errorf("Unexpected diff: %q", diff)
+	}
+	if a, r := DiffStat(diff); a != 2 || r != 0 {
+		t.Errorf("Unexpected stat: +%d -%d", a, r)
+	}
+
+	if diff := Diff("a\nb\nc\n", "a\nx\nc\n"); diff != "-b\n+x\n" {
+		t.Errorf("Unexpected replace diff: %q", diff)
+	}
+	if diff := Diff("", "new\n"); diff != "+new\n" {
+		t.Errorf("Unexpected create diff: %q", diff)
+	}
+}
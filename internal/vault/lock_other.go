@@ -0,0 +1,8 @@
+//go:build !unix
+
+package vault
+
+// lockFile is a no-op where flock is unavailable; the in-process lock still applies.
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,36 @@
+package vault
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+)
+
+var reNonSlug = regexp.MustCompile("[^a-z0-9]+")
+
+// NotePath is where a new note goes, relative to the vault root:
+// YYYYMMDD-kebab-title.md, inside a folder named after the category
+// (except "idea", which lives at the root).
+func NotePath(category, title string, day time.Time) string {
+	filename := fmt.Sprintf("%s-%s.md", day.Format("20060102"), Kebab(title))
+	if category == "idea" {
+		return filename
+	}
+	return filepath.Join(category, filename)
+}
+
+// NewNote renders the template every new note starts from: title, metadata
+// and all spec sections in order, with notas (may be empty) under ## Notas.
+func NewNote(title, category, notas string, day time.Time) []byte {
+	return markdown.NewDocument(title, day.Format("2006-01-02"), category, notas).Render()
+}
+
+// Kebab turns a title into the slug used in filenames ("Mi Idea!" → "mi-idea").
+func Kebab(s string) string {
+	s = strings.ToLower(s)
+	return strings.Trim(reNonSlug.ReplaceAllString(s, "-"), "-")
+}
@@ -0,0 +1,299 @@
+// Package vault is the single write path for note files. Writes are atomic
+// (temp file + fsync + rename, or hard link for new notes), serialized per note (in-process mutex plus an
+// advisory file lock for other processes) and refused when the file changed on
+// disk behind the index's back.
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/eliseohh/zettelcornelbot/internal/index"
+)
+
+// LockDir holds the advisory lock files, relative to the vault root.
+// Dot-directories are skipped by the indexer and the watcher.
+const LockDir = ".zettel/locks"
+
+var (
+	// ErrConflict: the note on disk no longer matches what the index (or the caller) last saw.
+	ErrConflict = errors.New("note changed on disk since it was read")
+	ErrExists   = errors.New("note already exists")
+	ErrOutside  = errors.New("path is outside the vault")
+)
+
+type Vault struct {
+	root string
+	db   *index.DB // Source of the last known hash; nil disables the check
+
+	mu    sync.Mutex
+	locks map[string]*noteLock
+}
+
+type noteLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func New(root string, db *index.DB) *Vault {
+	return &Vault{root: root, db: db, locks: make(map[string]*noteLock)}
+}
+
+// Update reads the note, passes its content to edit and atomically replaces
+// the file with the result. The whole cycle holds the note's lock.
+//
+// ErrConflict is returned (and nothing written) when the file doesn't match the
+// hash stored in nodes, or when it changes while edit runs.
+func (v *Vault) Update(path string, edit func(old []byte) ([]byte, error)) error {
+	unlock, err := v.lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	old, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	hash := index.HashContent(old)
+	if err := v.checkIndexed(path, hash); err != nil {
+		return err
+	}
+
+	content, err := edit(old)
+	if err != nil {
+		return err
+	}
+
+	// Another process may not honour the lock (editors don't): re-check right before replacing
+	if err := expect(path, hash); err != nil {
+		return err
+	}
+	return writeAtomic(path, content)
+}
+
+// Create writes a new note; ErrExists if the path is taken, ErrOutside if it
+// is not inside the vault root.
+func (v *Vault) Create(path string, content []byte) error {
+	if !v.contains(path) {
+		return ErrOutside
+	}
+	unlock, err := v.lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := os.Stat(path); err == nil {
+		return ErrExists
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return createAtomic(path, content)
+}
+
+// Replace writes content only if the file still hashes to expectHash
+// (e.g. restoring a previous version).
+func (v *Vault) Replace(path, expectHash string, content []byte) error {
+	unlock, err := v.lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := expect(path, expectHash); err != nil {
+		return err
+	}
+	return writeAtomic(path, content)
+}
+
+// Remove deletes the note only if it still hashes to expectHash.
+func (v *Vault) Remove(path, expectHash string) error {
+	unlock, err := v.lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := expect(path, expectHash); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// contains reports whether path lies inside the vault root.
+func (v *Vault) contains(path string) bool {
+	rel, err := filepath.Rel(v.root, path)
+	return err == nil && rel != "." && !filepath.IsAbs(rel) &&
+		rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func expect(path, hash string) error {
+	current, err := os.ReadFile(path)
+	if err != nil || index.HashContent(current) != hash {
+		return ErrConflict
+	}
+	return nil
+}
+
+// checkIndexed compares the file with the hash the indexer recorded.
+// Notes not indexed yet have nothing to compare against, and neither do notes
+// that failed to parse: their row keeps the hash of the last valid version,
+// which a reindex cannot refresh until the note is fixed (e.g. by this edit).
+func (v *Vault) checkIndexed(path, hash string) error {
+	if v.db == nil {
+		return nil
+	}
+	rel, err := filepath.Rel(v.root, path)
+	if err != nil {
+		return nil
+	}
+	if failed, err := v.db.ParseFailed(rel); err != nil || failed {
+		return err
+	}
+	indexed, err := v.db.NodeHash(rel)
+	if err != nil {
+		return err
+	}
+	if indexed != "" && indexed != hash {
+		return ErrConflict
+	}
+	return nil
+}
+
+// lock takes the in-process mutex for path, then the cross-process file lock.
+func (v *Vault) lock(path string) (func(), error) {
+	key := filepath.Clean(path)
+
+	v.mu.Lock()
+	l := v.locks[key]
+	if l == nil {
+		l = &noteLock{}
+		v.locks[key] = l
+	}
+	l.refs++
+	v.mu.Unlock()
+
+	release := func() {
+		l.mu.Unlock()
+		v.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(v.locks, key)
+		}
+		v.mu.Unlock()
+	}
+
+	l.mu.Lock()
+	unlockFile, err := lockFile(v.lockPath(key))
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("lock %s: %w", filepath.Base(path), err)
+	}
+	return func() {
+		unlockFile()
+		release()
+	}, nil
+}
+
+// lockPath maps a note to its sidecar lock file (flat, named by path hash).
+func (v *Vault) lockPath(path string) string {
+	return filepath.Join(v.root, LockDir, index.HashContent([]byte(path))[:16]+".lock")
+}
+
+// writeAtomic replaces path so readers see either the old or the new content, never a mix.
+func writeAtomic(path string, content []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := writeTemp(path, content, mode)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp) // No-op after a successful rename
+
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// createAtomic is writeAtomic for a path that must not exist yet. The file is
+// published with a hard link, which fails if anyone (an editor included)
+// created path meanwhile; rename would silently replace it.
+func createAtomic(path string, content []byte) error {
+	tmp, err := writeTemp(path, content, 0644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	err = os.Link(tmp, path)
+	if errors.Is(err, fs.ErrExist) {
+		return ErrExists
+	}
+	if err != nil {
+		// No hard links on this filesystem: still exclusive, just not atomic
+		return createExclusive(path, content)
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+func createExclusive(path string, content []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		return ErrExists
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeTemp writes content to a synced hidden file next to path and returns its name.
+func writeTemp(path string, content []byte, mode os.FileMode) (name string, err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(content); err != nil {
+		return "", err
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return "", err
+	}
+	if err = tmp.Sync(); err != nil {
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// syncDir persists a rename or link in dir (best effort: not supported everywhere).
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,183 @@
+package vault
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eliseohh/zettelcornelbot/internal/index"
+	"github.com/eliseohh/zettelcornelbot/internal/markdown"
+)
+
+func newTestDB(t *testing.T) *index.DB {
+	t.Helper()
+	db, err := index.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func appendLine(line string) func([]byte) ([]byte, error) {
+	return func(old []byte) ([]byte, error) {
+		return append(old, []byte(line+"\n")...), nil
+	}
+}
+
+func TestCreateAndUpdate(t *testing.T) {
+	root := t.TempDir()
+	v := New(root, nil)
+	path := filepath.Join(root, "libro", "a.md")
+
+	if err := v.Create(path, []byte("# A\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Create(path, []byte("# B\n")); err != ErrExists {
+		t.Errorf("Expected ErrExists, got %v", err)
+	}
+	if err := v.Update(path, appendLine("more")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "# A\nmore\n" {
+		t.Errorf("Unexpected content: %q", got)
+	}
+
+	// Paths that escape the root are refused before anything is created
+	outside := filepath.Join(root, "..", filepath.Base(root)+"-escaped", "x.md")
+	if err := v.Create(outside, []byte("# X\n")); err != ErrOutside {
+		t.Errorf("Expected ErrOutside, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Dir(outside)); !os.IsNotExist(err) {
+		t.Errorf("Create touched a folder outside the vault: %v", err)
+	}
+
+	// A failing edit leaves the file alone
+	if err := v.Update(path, func([]byte) ([]byte, error) { return nil, fmt.Errorf("nope") }); err == nil {
+		t.Error("Expected edit error")
+	}
+	if got, _ := os.ReadFile(path); string(got) != "# A\nmore\n" {
+		t.Errorf("File touched by failed edit: %q", got)
+	}
+
+	// An editor creating the file after Create's existence check still wins
+	raced := filepath.Join(root, "libro", "b.md")
+	os.WriteFile(raced, []byte("# Editor\n"), 0644)
+	if err := createAtomic(raced, []byte("# Bot\n")); err != ErrExists {
+		t.Errorf("Expected ErrExists, got %v", err)
+	}
+	if got, _ := os.ReadFile(raced); string(got) != "# Editor\n" {
+		t.Errorf("Editor's file overwritten: %q", got)
+	}
+
+	// No temp files left behind
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 2 {
+		t.Errorf("Unexpected files: %v", entries)
+	}
+}
+
+func TestConcurrentUpdatesSerialize(t *testing.T) {
+	root := t.TempDir()
+	v := New(root, nil)
+	path := filepath.Join(root, "n.md")
+	v.Create(path, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := v.Update(path, appendLine(fmt.Sprintf("line %d", i))); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	got, _ := os.ReadFile(path)
+	if n := strings.Count(string(got), "\n"); n != 20 {
+		t.Errorf("Expected 20 lines, got %d (lost update):\n%s", n, got)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	root := t.TempDir()
+	db := newTestDB(t)
+	v := New(root, db)
+	path := filepath.Join(root, "n.md")
+	os.WriteFile(path, []byte("indexed\n"), 0644)
+	db.Exec("INSERT INTO nodes (id, path, hash, last_mod) VALUES ('n', 'n.md', ?, 0)", index.HashContent([]byte("indexed\n")))
+
+	if err := v.Update(path, appendLine("ok")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The write above is not reindexed: the file no longer matches nodes.hash
+	if err := v.Update(path, appendLine("stale")); err != ErrConflict {
+		t.Errorf("Expected ErrConflict against the index, got %v", err)
+	}
+	db.Exec("UPDATE nodes SET hash = ? WHERE path = 'n.md'", index.HashContent([]byte("indexed\nok\n")))
+
+	// Someone edits the file while our edit is being computed
+	err := v.Update(path, func(old []byte) ([]byte, error) {
+		os.WriteFile(path, []byte("external\n"), 0644)
+		return append(old, "mine\n"...), nil
+	})
+	if err != ErrConflict {
+		t.Errorf("Expected ErrConflict for concurrent edit, got %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "external\n" {
+		t.Errorf("External edit overwritten: %q", got)
+	}
+}
+
+func TestUpdateInvalidNote(t *testing.T) {
+	root := t.TempDir()
+	db := newTestDB(t)
+	idx := index.NewIndexer(db)
+	idx.Out = io.Discard
+	v := New(root, db)
+	path := filepath.Join(root, "n.md")
+
+	note := "# N\nFecha: 2024-02-02\nTipo: idea\n\n## Notas\n\n## Cues\n- %s\n\n## Resumen\n\n## Enlaces\n"
+	os.WriteFile(path, []byte(fmt.Sprintf(note, "Why?")), 0644)
+	idx.Sync(root)
+	// Broken outside the bot: the row keeps the valid version's hash
+	os.WriteFile(path, []byte(fmt.Sprintf(note, "no mark")), 0644)
+	idx.Sync(root)
+
+	fix := func(old []byte) ([]byte, error) {
+		return []byte(strings.Replace(string(old), "no mark", "Fixed?", 1)), nil
+	}
+	if err := v.Update(path, fix); err != nil {
+		t.Fatalf("Expected the invalid note to be editable, got %v", err)
+	}
+	if err := idx.Sync(root); err != nil {
+		t.Fatal(err)
+	}
+	if cues, _ := db.Cues("n"); len(cues) != 1 || cues[0] != "Fixed?" {
+		t.Errorf("Expected the fix indexed, got %v", cues)
+	}
+}
+
+func TestNotePath(t *testing.T) {
+	day := time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC)
+	if got := NotePath("idea", "¡Mi Idea, 2!", day); got != "20240202-mi-idea-2.md" {
+		t.Errorf("Unexpected idea path: %s", got)
+	}
+	if got := NotePath("libro", "Dune", day); got != filepath.Join("libro", "20240202-dune.md") {
+		t.Errorf("Unexpected libro path: %s", got)
+	}
+	if _, err := markdown.Parse("x.md", NewNote("Dune", "libro", "First line.", day)); err != nil {
+		t.Errorf("Template must validate: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+// Package versioning keeps the vault in a git repository: every bot mutation
+// and every batch of external changes found by Sync becomes a commit.
+// It drives the local git binary; nothing is pushed.
+package versioning
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Author of every commit, so bot history is easy to tell from the user's own.
+const (
+	AuthorName  = "ZettelCornelBot"
+	AuthorEmail = "zettelbot@localhost"
+)
+
+// ErrNoHistory is returned by LastChange for files without commits.
+var ErrNoHistory = errors.New("no committed history")
+
+// Message is the structured commit message:
+//
+//	cue: 20240202-my-note
+//
+//	Command: cue
+//	Note: 20240202-my-note
+//	Chat: 7
+type Message struct {
+	Command string   // Bot command ("create", "link", "cue", "undo", ...) or "sync"
+	NoteID  string   // Empty for multi-note batches
+	ChatID  int64    // 0 for changes not made through a chat
+	Paths   []string // Listed in the body when there is no single NoteID
+}
+
+func (m Message) String() string {
+	sb := strings.Builder{}
+	switch {
+	case m.NoteID != "":
+		fmt.Fprintf(&sb, "%s: %s\n\n", m.Command, m.NoteID)
+	default:
+		fmt.Fprintf(&sb, "%s: %d file(s)\n\n", m.Command, len(m.Paths))
+	}
+	fmt.Fprintf(&sb, "Command: %s\n", m.Command)
+	if m.NoteID != "" {
+		fmt.Fprintf(&sb, "Note: %s\n", m.NoteID)
+	}
+	if m.ChatID != 0 {
+		fmt.Fprintf(&sb, "Chat: %d\n", m.ChatID)
+	}
+	if m.NoteID == "" {
+		for _, p := range m.Paths {
+			fmt.Fprintf(&sb, "Path: %s\n", p)
+		}
+	}
+	return sb.String()
+}
+
+// Repo is a vault's git repository. Share one per process: it serializes git calls.
+type Repo struct {
+	root string
+	mu   sync.Mutex // One git process at a time (index.lock)
+}
+
+// Open uses the git repository containing root, creating one at root if there is none.
+func Open(root string) (*Repo, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("versioning needs the git binary: %w", err)
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	r := &Repo{root: abs}
+
+	if _, err := r.git("rev-parse", "--git-dir"); err != nil {
+		if _, err := r.git("init", "--quiet"); err != nil {
+			return nil, err
+		}
+	}
+
+	// Keep bot internals (.zettel/locks) out of status without touching the user's .gitignore
+	gitDir, err := r.git("rev-parse", "--git-dir")
+	if err != nil {
+		return nil, err
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(abs, gitDir)
+	}
+	exclude := filepath.Join(gitDir, "info", "exclude")
+	if content, _ := os.ReadFile(exclude); !bytes.Contains(content, []byte(".zettel/")) {
+		os.MkdirAll(filepath.Dir(exclude), 0755)
+		f, err := os.OpenFile(exclude, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err == nil {
+			f.WriteString(".zettel/\n")
+			f.Close()
+		}
+	}
+	return r, nil
+}
+
+// Commit records the current state of paths (relative to root). Deleted files
+// are recorded as deletions; a commit with no actual change is skipped.
+func (r *Repo) Commit(msg Message, paths ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var spec []string
+	for _, p := range paths {
+		if _, err := os.Stat(filepath.Join(r.root, p)); err == nil || r.tracked(p) {
+			spec = append(spec, p)
+		}
+	}
+	if len(spec) == 0 {
+		return nil
+	}
+
+	if _, err := r.git(append([]string{"add", "-A", "--"}, spec...)...); err != nil {
+		return err
+	}
+	// Exit status 1 = staged changes for these paths
+	if _, err := r.git(append([]string{"diff", "--cached", "--quiet", "--"}, spec...)...); err == nil {
+		return nil
+	}
+
+	if msg.NoteID == "" && len(msg.Paths) == 0 {
+		msg.Paths = spec
+	}
+	args := []string{
+		"-c", "user.name=" + AuthorName, "-c", "user.email=" + AuthorEmail,
+		"commit", "--quiet", "--no-verify", "-m", msg.String(), "--",
+	}
+	_, err := r.git(append(args, spec...)...)
+	return err
+}
+
+// LastChange returns the latest commit touching path, with its patch.
+func (r *Repo) LastChange(path string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.git("rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return "", ErrNoHistory // Fresh repository
+	}
+	out, err := r.git("log", "-1", "-p", "--format=%h %ad%n%B", "--date=format:%Y-%m-%d %H:%M", "--", path)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(out) == "" {
+		return "", ErrNoHistory
+	}
+	return out, nil
+}
+
+func (r *Repo) tracked(path string) bool {
+	out, err := r.git("ls-files", "--", path)
+	return err == nil && strings.TrimSpace(out) != ""
+}
+
+func (r *Repo) git(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", r.root}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
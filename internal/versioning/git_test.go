@@ -0,0 +1,79 @@
+package versioning
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestRepo(t *testing.T) (*Repo, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	r, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, root
+}
+
+func TestCommitAndLastChange(t *testing.T) {
+	r, root := newTestRepo(t)
+
+	if _, err := r.LastChange("a.md"); err != ErrNoHistory {
+		t.Fatalf("Expected ErrNoHistory, got %v", err)
+	}
+
+	os.WriteFile(filepath.Join(root, "a.md"), []byte("# A\n"), 0644)
+	os.WriteFile(filepath.Join(root, "b.md"), []byte("# B\n"), 0644)
+	os.MkdirAll(filepath.Join(root, ".zettel", "locks"), 0755)
+	os.WriteFile(filepath.Join(root, ".zettel", "locks", "x.lock"), nil, 0644)
+
+	msg := Message{Command: "create", NoteID: "a", ChatID: 7}
+	if err := r.Commit(msg, "a.md"); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.LastChange("a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"create: a", "Command: create", "Note: a", "Chat: 7", "+# A"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("LastChange missing %q:\n%s", want, out)
+		}
+	}
+
+	// Only the named paths are committed; lock files are never tracked
+	if r.tracked("b.md") || r.tracked(".zettel/locks/x.lock") {
+		t.Error("Commit picked up files outside its paths")
+	}
+
+	// Unchanged content: no empty commit
+	if err := r.Commit(Message{Command: "sync"}, "a.md"); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := r.git("rev-list", "--count", "HEAD"); strings.TrimSpace(out) != "1" {
+		t.Errorf("Expected 1 commit, got %s", out)
+	}
+
+	// Batch with a deletion
+	os.Remove(filepath.Join(root, "a.md"))
+	if err := r.Commit(Message{Command: "sync"}, "a.md", "b.md", "gone.md"); err != nil {
+		t.Fatal(err)
+	}
+	out, _ = r.git("log", "-1", "--format=%B")
+	if !strings.HasPrefix(out, "sync: 2 file(s)") || !strings.Contains(out, "Path: a.md\nPath: b.md\n") {
+		t.Errorf("Unexpected batch message:\n%s", out)
+	}
+	if r.tracked("a.md") || !r.tracked("b.md") {
+		t.Error("Batch did not record deletion and addition")
+	}
+	out, _ = r.git("log", "-1", "--format=%an <%ae>")
+	if strings.TrimSpace(out) != AuthorName+" <"+AuthorEmail+">" {
+		t.Errorf("Unexpected author: %s", out)
+	}
+}